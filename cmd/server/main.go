@@ -5,9 +5,14 @@ import (
 	"Cyber-chase/internal/company"
 	"Cyber-chase/internal/models"
 	"Cyber-chase/internal/pkg"
+	"Cyber-chase/internal/pkg/audit"
+	"Cyber-chase/internal/pkg/auth"
+	"Cyber-chase/internal/pkg/mail"
+	"Cyber-chase/internal/pkg/ratelimit"
 	"Cyber-chase/internal/repository"
 	"Cyber-chase/internal/service"
 	"Cyber-chase/internal/team"
+	"context"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -28,38 +33,71 @@ func main() {
 		panic("Error loading .env file")
 	}
 
-	db.AutoMigrate(&models.Contest{}, &models.Company{}, &models.Task{}, &models.Team{}, &models.TeamAnswer{}, &models.TeamTaskSession{})
+	if err := repository.PrepareSchema(db); err != nil {
+		log.Fatalf("Failed to prepare schema: %v", err)
+	}
+	db.AutoMigrate(&models.Contest{}, &models.Company{}, &models.Task{}, &models.Team{}, &models.TeamAnswer{}, &models.TeamTaskSession{}, &models.TeamSubmission{}, &models.TaskHint{}, &models.TeamMember{}, &models.TeamInvite{}, &models.TeamAdjustment{}, &models.ApprovalRequest{}, &models.CompanyUser{}, &models.Admin{}, &models.AdminLoginAttempt{}, &models.RefreshToken{}, &models.AnswerFlag{}, &models.AuditLog{}, &models.OutboxEmail{}, &models.ServiceKey{})
+	if err := repository.MigrateLegacyData(db); err != nil {
+		log.Fatalf("Failed to migrate legacy data: %v", err)
+	}
 
 	repo := repository.NewRepository(db)
-	adminHandler := admin.NewAdminHandler(repo, "admin", "0000")
-
-	mailService := company.NewSMTPMailer(
-		os.Getenv("SMTP_HOST"),
-		os.Getenv("SMTP_PORT"),
-		os.Getenv("SMTP_USER"),
-		os.Getenv("SMTP_PASS"),
-	)
+	if err := admin.Bootstrap(context.Background(), repo, os.Getenv("ADMIN_BOOTSTRAP_USERNAME"), os.Getenv("ADMIN_BOOTSTRAP_PASSWORD")); err != nil {
+		log.Fatalf("Failed to bootstrap admin: %v", err)
+	}
+	if err := company.ReissueLegacyCredentials(context.Background(), repo); err != nil {
+		log.Fatalf("Failed to reissue legacy company credentials: %v", err)
+	}
+	tokens, err := auth.NewManagerFromEnv(repo)
+	if err != nil {
+		log.Fatalf("Failed to configure tokens: %v", err)
+	}
+	authHandler := auth.NewHandler(tokens)
+	loginLimiter := ratelimit.NewLimiter(ratelimit.LoginConfig, ratelimit.NewMemoryStore())
+	resetLimiter := ratelimit.NewLimiter(ratelimit.ResetRequestConfig, ratelimit.NewMemoryStore())
+	auditRecorder := audit.NewRecorder(repo)
+	auditHandler := audit.NewHandler(repo)
+	adminHandler := admin.NewAdminHandler(repo, tokens, auditRecorder)
+
+	mailer, err := mail.NewSender(mail.LoadTransportConfig())
+	if err != nil {
+		log.Fatalf("Failed to configure mail transport: %v", err)
+	}
+	mailRenderer, err := mail.NewRenderer(os.Getenv("MAIL_TEMPLATE_DIR"))
+	if err != nil {
+		log.Fatalf("Failed to load mail templates: %v", err)
+	}
+	outboxWorker := mail.NewWorker(repo, mailer, mailRenderer, mail.LoadOutboxConfig())
+	go outboxWorker.Run(context.Background())
+	mailHandler := mail.NewHandler(repo)
 
-	companyTaskHandler := company.NewCompanyTaskHandler(repo)
 	teamRepo := repository.NewTeamRepository(db)
-	teamService := service.NewTeamService(teamRepo, repo, db, mailService)
-	companyHandler := company.NewCompanyHandler(repo, mailService, teamService)
-	teamHandler := team.NewTeamHandler(teamService)
-	jwtSecret := os.Getenv("JWT_SECRET")
-
-	router := gin.Default()
-
-	router.Use(cors.Default())
+	teamService := service.NewTeamService(teamRepo, repo, db)
+	teamService.SetRecorder(auditRecorder)
 
 	botToken := os.Getenv("BOT_TOKEN")
 	if botToken == "" {
 		log.Fatal("BOT_TOKEN environment variable not set")
 	}
 
-	bot, err := team.NewTelegramBot(botToken, teamService)
+	bot, err := team.NewTelegramBot(botToken, teamService, loginLimiter)
 	if err != nil {
 		log.Fatalf("Failed to create telegram bot: %v", err)
 	}
+	teamService.SetNotifier(bot)
+
+	companyTaskHandler := company.NewCompanyTaskHandler(repo, bot.Username(), auditRecorder)
+	companyHandler := company.NewCompanyHandler(repo, teamService, tokens, auditRecorder)
+	teamHandler := team.NewTeamHandler(teamService, tokens)
+	teamAdminHandler := admin.NewTeamAdminHandler(teamRepo, teamService, auditRecorder)
+
+	router := gin.Default()
+	// Лимитеры входа считают попытки по c.ClientIP(), X-Forwarded-For принимается только от TRUSTED_PROXIES
+	if err := router.SetTrustedProxies(ratelimit.TrustedProxiesFromEnv()); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	router.Use(cors.Default())
 
 	log.Println("Bot is now running. Press Ctrl+C to exit.")
 	go func() {
@@ -71,15 +109,34 @@ func main() {
 
 	public := router.Group("/api/v1")
 	{
-		public.POST("/admin/login", adminHandler.AdminLogin)
-		public.POST("/company/login", companyHandler.CompanyLogin)
+		public.POST("/auth/refresh", authHandler.Refresh)
+		public.POST("/auth/logout", authHandler.Logout)
+		public.POST("/admin/login", ratelimit.LoginGuard(loginLimiter, "admin", "username"), adminHandler.AdminLogin)
+		public.POST("/company/login", ratelimit.LoginGuard(loginLimiter, "company", "email"), companyHandler.CompanyLogin)
+		public.POST("/company/invite/accept", companyHandler.AcceptInvite)
+		public.POST("/company/setup", companyHandler.SetupAccount)
 		public.POST("/team/register", teamHandler.RegisterTeam)
-		public.POST("/team/login", teamHandler.LoginTeam)
+		public.POST("/team/login", ratelimit.LoginGuard(loginLimiter, "team", "email"), teamHandler.LoginTeam)
+		public.POST("/team/reset-password", ratelimit.RequestGuard(resetLimiter, "team_reset", "email"), teamHandler.RequestPasswordReset)
+		public.POST("/team/reset-password/confirm", ratelimit.LoginGuard(loginLimiter, "team_reset", "email"), teamHandler.ConfirmPasswordReset)
 	}
 
 	adminRoutes := router.Group("/api/v1/admin")
-	adminRoutes.Use(pkg.JWTAuthMiddleware(jwtSecret))
+	adminRoutes.Use(pkg.Authorize(tokens, pkg.AdminPolicy))
 	{
+		adminRoutes.GET("/admins", adminHandler.GetAdmins)
+		adminRoutes.POST("/admins", adminHandler.CreateAdmin)
+		adminRoutes.DELETE("/admins/:id", adminHandler.DeleteAdmin)
+		adminRoutes.POST("/change-password", adminHandler.ChangePassword)
+		adminRoutes.GET("/login-audit", adminHandler.GetLoginAudit)
+		adminRoutes.GET("/service-keys", adminHandler.GetServiceKeys)
+		adminRoutes.POST("/service-keys", adminHandler.CreateServiceKey)
+		adminRoutes.DELETE("/service-keys/:id", adminHandler.RevokeServiceKey)
+		adminRoutes.GET("/audit", auditHandler.GetLogs)
+		adminRoutes.GET("/audit/export", auditHandler.ExportCSV)
+		adminRoutes.GET("/emails", mailHandler.GetEmails)
+		adminRoutes.POST("/emails/:id/resend", mailHandler.ResendEmail)
+
 		adminRoutes.POST("/companies", companyHandler.CreateCompany)
 		adminRoutes.GET("/companies", companyHandler.GetAllCompanies)
 		adminRoutes.PUT("/companies/:id", companyHandler.UpdateCompany)
@@ -93,23 +150,78 @@ func main() {
 
 		adminRoutes.POST("/contests/:id/start", adminHandler.StartContest)
 		adminRoutes.POST("/contests/:id/end", adminHandler.EndContest)
+		adminRoutes.GET("/contests/:id/leaderboard", teamAdminHandler.GetLeaderboard)
+		adminRoutes.POST("/contests/:id/reminder", teamAdminHandler.SendContestReminder)
+		adminRoutes.POST("/contests/:id/results-email", teamAdminHandler.SendContestResults)
+
+		adminRoutes.GET("/teams", teamAdminHandler.GetTeams)
+		adminRoutes.GET("/teams/:id", teamAdminHandler.GetTeam)
+		adminRoutes.PUT("/teams/:id", teamAdminHandler.UpdateTeam)
+		adminRoutes.POST("/teams/:id/unlink-telegram", teamAdminHandler.UnlinkTelegram)
+		adminRoutes.POST("/teams/:id/reset-password", teamAdminHandler.ResetPassword)
+		adminRoutes.POST("/teams/:id/disqualify", teamAdminHandler.DisqualifyTeam)
+		adminRoutes.POST("/teams/:id/reinstate", teamAdminHandler.ReinstateTeam)
+		adminRoutes.DELETE("/teams/:id", teamAdminHandler.DeleteTeam)
+		adminRoutes.GET("/teams/:id/adjustments", teamAdminHandler.GetAdjustments)
+		adminRoutes.POST("/teams/:id/adjustments", teamAdminHandler.AddAdjustment)
+		adminRoutes.DELETE("/teams/:id/adjustments/:adjustmentID", teamAdminHandler.RevokeAdjustment)
+		adminRoutes.GET("/flags", teamAdminHandler.GetFlaggedSessions)
+	}
+
+	internalRoutes := router.Group("/api/v1/internal")
+	{
+		internalRoutes.GET("/contests/:id/leaderboard", pkg.RequireServiceKey(repo, auth.ScopeLeaderboardRead), teamAdminHandler.GetLeaderboard)
+		internalRoutes.GET("/teams/:id", pkg.RequireServiceKey(repo, auth.ScopeTeamsRead), teamAdminHandler.GetTeam)
+	}
+
+	teamRoutes := router.Group("/api/v1/team")
+	teamRoutes.Use(pkg.Authorize(tokens, pkg.TeamPolicy))
+	{
+		teamRoutes.GET("/profile", teamHandler.GetProfile)
+		teamRoutes.POST("/change-password", teamHandler.ChangePassword)
+
+		teamRoutes.POST("/contest/join", teamHandler.JoinContest)
+
+		teamRoutes.GET("/task", teamHandler.GetCurrentTask)
+		teamRoutes.POST("/task", teamHandler.GetNextTask)
+		teamRoutes.POST("/task/answer", teamHandler.SubmitAnswer)
+
+		teamRoutes.GET("/history", teamHandler.GetHistory)
 	}
 
 	companyRoutes := router.Group("/api/v1/company")
-	companyRoutes.Use(pkg.CompanyAuthMiddleware(jwtSecret))
+	companyRoutes.Use(pkg.Authorize(tokens, pkg.CompanyPolicy))
 	{
+		ownerOnly := pkg.Authorize(tokens, pkg.CompanyRolePolicy(models.CompanyRoleOwner))
+		operatorOrOwner := pkg.Authorize(tokens, pkg.CompanyRolePolicy(models.CompanyRoleOwner, models.CompanyRoleOperator))
+
 		companyRoutes.POST("/change-password", companyHandler.ChangePassword)
 
 		companyRoutes.GET("/location", companyHandler.GetMapLink)
+		companyRoutes.PUT("/capacity", ownerOnly, companyHandler.UpdateCapacity)
+		companyRoutes.GET("/dashboard", companyHandler.GetDashboard)
 
-		companyRoutes.POST("/tasks", companyTaskHandler.CreateTask)
+		companyRoutes.GET("/users", ownerOnly, companyHandler.GetUsers)
+		companyRoutes.POST("/users", ownerOnly, companyHandler.InviteUser)
+		companyRoutes.PUT("/users/:userID", ownerOnly, companyHandler.UpdateUserRole)
+		companyRoutes.DELETE("/users/:userID", ownerOnly, companyHandler.DeleteUser)
+
+		companyRoutes.POST("/tasks", ownerOnly, companyTaskHandler.CreateTask)
 		companyRoutes.GET("/tasks", companyTaskHandler.GetCompanyTasks)
 		companyRoutes.GET("/tasks/:id/file", companyTaskHandler.GetTaskFile)
-		companyRoutes.PUT("/tasks/:id", companyTaskHandler.UpdateTask)
-		companyRoutes.DELETE("/tasks/:id", companyTaskHandler.DeleteTask)
-
-		companyRoutes.GET("/teams", companyHandler.GetUnassignedTeams)
-		companyRoutes.POST("/teams/:teamID/approve", companyHandler.ApproveTeam)
+		companyRoutes.GET("/tasks/:id/qr", companyTaskHandler.GetTaskQRCode)
+		companyRoutes.GET("/tasks/:id/results", companyHandler.GetTaskResults)
+		companyRoutes.PUT("/tasks/:id", ownerOnly, companyTaskHandler.UpdateTask)
+		companyRoutes.DELETE("/tasks/:id", ownerOnly, companyTaskHandler.DeleteTask)
+
+		companyRoutes.GET("/teams", companyHandler.GetTeamRequests)
+		companyRoutes.POST("/teams/:teamID/approve", operatorOrOwner, companyHandler.ApproveTeam)
+		companyRoutes.POST("/teams/:teamID/reject", operatorOrOwner, companyHandler.RejectTeam)
+
+		companyRoutes.GET("/submissions", companyHandler.GetSubmissions)
+		companyRoutes.GET("/submissions/:id/file", companyHandler.GetSubmissionFile)
+		companyRoutes.POST("/submissions/:id/accept", operatorOrOwner, companyHandler.AcceptSubmission)
+		companyRoutes.POST("/submissions/:id/reject", operatorOrOwner, companyHandler.RejectSubmission)
 	}
 
 	router.Run(":8080")
@@ -0,0 +1,221 @@
+package admin
+
+import (
+	"Cyber-chase/internal/models"
+	"Cyber-chase/internal/pkg"
+	"Cyber-chase/internal/pkg/auth"
+	"Cyber-chase/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinAdminPasswordLength is the shortest password accepted for admin accounts
+const MinAdminPasswordLength = 10
+
+// Bootstrap creates the first admin account when there are none yet.
+// Credentials come from the environment, nothing is created if they are missing.
+func Bootstrap(ctx context.Context, repo *repository.Repository, username, password string) error {
+	count, err := repo.CountAdmins(ctx)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if username == "" || password == "" {
+		log.Println("No admin accounts exist: set ADMIN_BOOTSTRAP_USERNAME and ADMIN_BOOTSTRAP_PASSWORD to create the first one")
+		return nil
+	}
+	if len(password) < MinAdminPasswordLength {
+		return fmt.Errorf("bootstrap admin password must be at least %d characters", MinAdminPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	if err := repo.CreateAdmin(ctx, &models.Admin{Username: username, PasswordHash: string(hash)}); err != nil {
+		return err
+	}
+
+	log.Printf("Bootstrap admin %q created", username)
+	return nil
+}
+
+func adminResponse(admin *models.Admin) gin.H {
+	return gin.H{
+		"id":            admin.ID,
+		"username":      admin.Username,
+		"last_login_at": admin.LastLoginAt,
+		"created_at":    admin.CreatedAt,
+	}
+}
+
+func adminClaims(admin *models.Admin) auth.Claims {
+	return auth.Claims{
+		Role:             auth.RoleAdmin,
+		Username:         admin.Username,
+		TokenVersion:     admin.TokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: admin.ID.String()},
+	}
+}
+
+func (h *AdminHandler) GetAdmins(c *gin.Context) {
+	admins, err := h.repo.GetAllAdmins(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := make([]gin.H, 0, len(admins))
+	for i := range admins {
+		response = append(response, adminResponse(&admins[i]))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *AdminHandler) CreateAdmin(c *gin.Context) {
+	var input struct {
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(input.Password) < MinAdminPasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Password must be at least %d characters", MinAdminPasswordLength)})
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password"})
+		return
+	}
+
+	admin := &models.Admin{Username: input.Username, PasswordHash: string(hash)}
+	if err := h.repo.CreateAdmin(c.Request.Context(), admin); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Admin already exists"})
+		return
+	}
+	h.audit.Record(c, "admin.create", "admin", admin.ID, nil, adminResponse(admin))
+
+	c.JSON(http.StatusCreated, adminResponse(admin))
+}
+
+func (h *AdminHandler) DeleteAdmin(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	if id == pkg.AdminID(c) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot delete yourself"})
+		return
+	}
+
+	admin, err := h.repo.GetAdminByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Admin not found"})
+		return
+	}
+
+	if err := h.repo.DeleteAdmin(c.Request.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrLastAdmin) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Cannot delete the last admin"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.audit.Record(c, "admin.delete", "admin", id, adminResponse(admin), nil)
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+func (h *AdminHandler) ChangePassword(c *gin.Context) {
+	id := pkg.AdminID(c)
+
+	var req struct {
+		OldPassword string `json:"old_password" binding:"required"`
+		NewPassword string `json:"new_password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(req.NewPassword) < MinAdminPasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Password must be at least %d characters", MinAdminPasswordLength)})
+		return
+	}
+
+	admin, err := h.repo.GetAdminByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Admin not found"})
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(req.OldPassword)); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid old password"})
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process new password"})
+		return
+	}
+
+	admin.PasswordHash = string(hash)
+	// Previously issued tokens stop working after a password change
+	admin.TokenVersion++
+	if err := h.repo.UpdateAdmin(c.Request.Context(), admin); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		return
+	}
+	h.audit.Record(c, "admin.change_password", "admin", admin.ID, nil, nil)
+
+	pair, err := h.tokens.IssuePair(c.Request.Context(), adminClaims(admin))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	response := pair.Fields()
+	response["status"] = "password_changed"
+	c.JSON(http.StatusOK, response)
+}
+
+// GetLoginAudit returns the latest admin login attempts, ?limit= defaults to 100
+func (h *AdminHandler) GetLoginAudit(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Limit must be between 1 and 1000"})
+		return
+	}
+
+	attempts, err := h.repo.GetAdminLoginAttempts(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, attempts)
+}
@@ -2,10 +2,12 @@ package admin
 
 import (
 	"Cyber-chase/internal/models"
+	"Cyber-chase/internal/pkg/audit"
+	"Cyber-chase/internal/pkg/auth"
 	"Cyber-chase/internal/repository"
-	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/crypto/bcrypt"
+	"log"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,25 +15,23 @@ import (
 )
 
 type AdminHandler struct {
-	repo      *repository.Repository
-	adminUser string
-	adminPass string
-	jwtSecret string
+	repo   *repository.Repository
+	tokens *auth.Manager
+	audit  *audit.Recorder
 }
 
-func NewAdminHandler(repo *repository.Repository, user, pass string) *AdminHandler {
+func NewAdminHandler(repo *repository.Repository, tokens *auth.Manager, recorder *audit.Recorder) *AdminHandler {
 	return &AdminHandler{
-		repo:      repo,
-		adminUser: user,
-		adminPass: pass,
-		jwtSecret: os.Getenv("JWT_SECRET"),
+		repo:   repo,
+		tokens: tokens,
+		audit:  recorder,
 	}
 }
 
 func (h *AdminHandler) AdminLogin(c *gin.Context) {
 	var req struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -39,29 +39,47 @@ func (h *AdminHandler) AdminLogin(c *gin.Context) {
 		return
 	}
 
-	if req.Username != h.adminUser || req.Password != h.adminPass {
+	attempt := &models.AdminLoginAttempt{
+		Username:  req.Username,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+
+	admin, err := h.repo.GetAdminByUsername(c.Request.Context(), req.Username)
+	if err == nil {
+		attempt.AdminID = &admin.ID
+		err = bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(req.Password))
+	}
+	attempt.Success = err == nil
+
+	if logErr := h.repo.CreateAdminLoginAttempt(c.Request.Context(), attempt); logErr != nil {
+		log.Printf("Failed to record admin login attempt: %v", logErr)
+	}
+
+	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":  "admin",
-		"role": "admin",
-		"exp":  time.Now().Add(time.Hour * 8).Unix(),
-	})
+	now := time.Now()
+	admin.LastLoginAt = &now
+	if err := h.repo.UpdateAdmin(c.Request.Context(), admin); err != nil {
+		log.Printf("Failed to update admin last login: %v", err)
+	}
 
-	tokenString, err := token.SignedString([]byte(h.jwtSecret))
+	pair, err := h.tokens.IssuePair(c.Request.Context(), adminClaims(admin))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": tokenString})
+	c.JSON(http.StatusOK, pair)
 }
 
 func (h *AdminHandler) CreateContest(c *gin.Context) {
 	var input struct {
-		Name string `json:"name" binding:"required"`
+		Name      string `json:"name" binding:"required"`
+		TaskOrder string `json:"task_order"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -69,19 +87,37 @@ func (h *AdminHandler) CreateContest(c *gin.Context) {
 		return
 	}
 
+	if input.TaskOrder == "" {
+		input.TaskOrder = models.TaskOrderFixed
+	}
+	if !isValidTaskOrder(input.TaskOrder) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task order"})
+		return
+	}
+
 	contest := &models.Contest{
-		Name:   input.Name,
-		Status: "pending",
+		Name:      input.Name,
+		Status:    "pending",
+		TaskOrder: input.TaskOrder,
 	}
 
 	if err := h.repo.CreateContest(c.Request.Context(), contest); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create contest"})
 		return
 	}
+	h.audit.Record(c, "contest.create", "contest", contest.ID, nil, contest)
 
 	c.JSON(http.StatusCreated, contest)
 }
 
+func isValidTaskOrder(mode string) bool {
+	switch mode {
+	case models.TaskOrderFixed, models.TaskOrderShuffled, models.TaskOrderProgressive:
+		return true
+	}
+	return false
+}
+
 func (h *AdminHandler) GetAllContests(c *gin.Context) {
 	contests, err := h.repo.GetAllContests(c.Request.Context())
 	if err != nil {
@@ -99,7 +135,8 @@ func (h *AdminHandler) UpdateContest(c *gin.Context) {
 	}
 
 	var input struct {
-		Name string `json:"name"`
+		Name      string `json:"name"`
+		TaskOrder string `json:"task_order"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -112,15 +149,24 @@ func (h *AdminHandler) UpdateContest(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Contest not found"})
 		return
 	}
+	before := *contest
 
 	if input.Name != "" {
 		contest.Name = input.Name
 	}
+	if input.TaskOrder != "" {
+		if !isValidTaskOrder(input.TaskOrder) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task order"})
+			return
+		}
+		contest.TaskOrder = input.TaskOrder
+	}
 
 	if err := h.repo.UpdateContest(c.Request.Context(), contest); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.audit.Record(c, "contest.update", "contest", contest.ID, before, contest)
 	c.JSON(http.StatusOK, gin.H{"status": "updated", "contest": contest})
 }
 
@@ -131,10 +177,17 @@ func (h *AdminHandler) DeleteContest(c *gin.Context) {
 		return
 	}
 
+	contest, err := h.repo.GetContestByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contest not found"})
+		return
+	}
+
 	if err := h.repo.DeleteContest(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.audit.Record(c, "contest.delete", "contest", id, contest, nil)
 	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
 }
 
@@ -156,6 +209,7 @@ func (h *AdminHandler) StartContest(c *gin.Context) {
 		return
 	}
 
+	before := *contest
 	contest.Status = "active"
 	contest.StartDate = time.Now()
 
@@ -163,6 +217,7 @@ func (h *AdminHandler) StartContest(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.audit.Record(c, "contest.start", "contest", contest.ID, before, contest)
 
 	c.JSON(http.StatusOK, gin.H{"status": "started", "contest": contest})
 }
@@ -185,6 +240,7 @@ func (h *AdminHandler) EndContest(c *gin.Context) {
 		return
 	}
 
+	before := *contest
 	contest.Status = "completed"
 	contest.EndDate = time.Now()
 
@@ -192,6 +248,7 @@ func (h *AdminHandler) EndContest(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.audit.Record(c, "contest.end", "contest", contest.ID, before, contest)
 
 	c.JSON(http.StatusOK, gin.H{"status": "ended", "contest": contest})
 }
@@ -0,0 +1,98 @@
+package admin
+
+import (
+	"Cyber-chase/internal/models"
+	"Cyber-chase/internal/pkg"
+	"Cyber-chase/internal/pkg/auth"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func serviceKeyResponse(key *models.ServiceKey) gin.H {
+	return gin.H{
+		"id":           key.ID,
+		"name":         key.Name,
+		"prefix":       key.Prefix,
+		"scopes":       key.Scopes,
+		"created_by":   key.CreatedBy,
+		"last_used_at": key.LastUsedAt,
+		"revoked":      key.RevokedAt != nil,
+		"revoked_at":   key.RevokedAt,
+		"created_at":   key.CreatedAt,
+	}
+}
+
+func (h *AdminHandler) GetServiceKeys(c *gin.Context) {
+	keys, err := h.repo.GetServiceKeys(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := make([]gin.H, 0, len(keys))
+	for i := range keys {
+		response = append(response, serviceKeyResponse(&keys[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"keys": response, "available_scopes": auth.Scopes})
+}
+
+// CreateServiceKey issues an internal API key, the plaintext key is returned only once
+func (h *AdminHandler) CreateServiceKey(c *gin.Context) {
+	var input struct {
+		Name   string   `json:"name" binding:"required"`
+		Scopes []string `json:"scopes" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, plaintext, err := auth.NewServiceKey(input.Name, pkg.AdminUsername(c), input.Scopes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.repo.CreateServiceKey(c.Request.Context(), key); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Service key with this name already exists"})
+		return
+	}
+	h.audit.Record(c, "service_key.create", "service_key", key.ID, nil, serviceKeyResponse(key))
+
+	response := serviceKeyResponse(key)
+	response["key"] = plaintext
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *AdminHandler) RevokeServiceKey(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	key, err := h.repo.GetServiceKeyByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service key not found"})
+		return
+	}
+	if key.RevokedAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Service key is already revoked"})
+		return
+	}
+
+	before := serviceKeyResponse(key)
+	now := time.Now()
+	key.RevokedAt = &now
+	if err := h.repo.UpdateServiceKey(c.Request.Context(), key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.audit.Record(c, "service_key.revoke", "service_key", key.ID, before, serviceKeyResponse(key))
+
+	c.JSON(http.StatusOK, gin.H{"status": "revoked", "key": serviceKeyResponse(key)})
+}
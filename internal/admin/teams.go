@@ -0,0 +1,503 @@
+package admin
+
+import (
+	"Cyber-chase/internal/models"
+	"Cyber-chase/internal/pkg"
+	"Cyber-chase/internal/pkg/audit"
+	"Cyber-chase/internal/pkg/mail"
+	"Cyber-chase/internal/repository"
+	"Cyber-chase/internal/service"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type TeamAdminHandler struct {
+	teams       repository.TeamRepository
+	teamService service.TeamService
+	audit       *audit.Recorder
+}
+
+func NewTeamAdminHandler(teams repository.TeamRepository, teamService service.TeamService, recorder *audit.Recorder) *TeamAdminHandler {
+	return &TeamAdminHandler{
+		teams:       teams,
+		teamService: teamService,
+		audit:       recorder,
+	}
+}
+
+func teamResponse(team *models.Team) gin.H {
+	return gin.H{
+		"id":                team.ID,
+		"name":              team.Name,
+		"email":             team.Email,
+		"language":          team.Language,
+		"status":            team.Status,
+		"disqualify_reason": team.DisqualifyReason,
+		"contest_id":        team.ContestID,
+		"company_id":        team.CompanyID,
+		"current_task_id":   team.CurrentTaskID,
+		"telegram_linked":   team.TelegramID != nil,
+		"points":            team.Points,
+		"total_duration":    team.TotalDuration.String(),
+		"reset_required":    team.ResetRequired,
+		"created_at":        team.CreatedAt,
+	}
+}
+
+// parseOptionalUUID parses a query value, an empty value means "not set"
+func parseOptionalUUID(value string) (*uuid.UUID, error) {
+	if value == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+func (h *TeamAdminHandler) GetTeams(c *gin.Context) {
+	var filter repository.TeamFilter
+	var err error
+
+	if filter.ContestID, err = parseOptionalUUID(c.Query("contest_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contest ID"})
+		return
+	}
+	if filter.CompanyID, err = parseOptionalUUID(c.Query("company_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
+		return
+	}
+	filter.Status = c.Query("status")
+
+	teams, err := h.teams.List(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := make([]gin.H, 0, len(teams))
+	for i := range teams {
+		response = append(response, teamResponse(&teams[i]))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *TeamAdminHandler) GetTeam(c *gin.Context) {
+	team, ok := h.findTeam(c)
+	if !ok {
+		return
+	}
+
+	members, err := h.teams.GetMembers(team.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := teamResponse(team)
+	response["members"] = members
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *TeamAdminHandler) UpdateTeam(c *gin.Context) {
+	team, ok := h.findTeam(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name      string  `json:"name"`
+		Email     string  `json:"email"`
+		Language  string  `json:"language"`
+		ContestID *string `json:"contest_id"`
+		CompanyID *string `json:"company_id"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before := teamResponse(team)
+	if input.Name != "" {
+		team.Name = input.Name
+	}
+	if input.Email != "" && input.Email != team.Email {
+		if existing, err := h.teams.FindByEmail(input.Email); err == nil && existing.ID != team.ID {
+			c.JSON(http.StatusConflict, gin.H{"error": "Team with this email already exists"})
+			return
+		}
+		team.Email = input.Email
+	}
+	if input.Language != "" {
+		team.Language = mail.NormalizeLanguage(input.Language)
+	}
+	if input.ContestID != nil {
+		contestID, err := parseOptionalUUID(*input.ContestID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contest ID"})
+			return
+		}
+		team.ContestID = contestID
+	}
+
+	if input.CompanyID != nil {
+		if !h.reassignTeam(c, team, *input.CompanyID) {
+			return
+		}
+	} else if err := h.teams.Update(team); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.audit.Record(c, "team.update", "team", team.ID, before, teamResponse(team))
+
+	c.JSON(http.StatusOK, gin.H{"status": "updated", "team": teamResponse(team)})
+}
+
+// reassignTeam saves the team and moves it to another company the same way an approval does:
+// the seat at the previous company is released and the new company's capacity is checked.
+// On failure the response is already written.
+func (h *TeamAdminHandler) reassignTeam(c *gin.Context, team *models.Team, rawCompanyID string) bool {
+	companyID, err := parseOptionalUUID(rawCompanyID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
+		return false
+	}
+
+	var request *models.ApprovalRequest
+	capacity := 0
+	switch {
+	case companyID == nil:
+		team.CompanyID = nil
+	case team.CompanyID != nil && *team.CompanyID == *companyID:
+		if err := h.teams.Update(team); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return false
+		}
+		return true
+	default:
+		company, err := h.teamService.GetCompanyByID(*companyID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+			return false
+		}
+		if team.ContestID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Team must join a contest before being assigned to a company"})
+			return false
+		}
+
+		now := time.Now()
+		request = &models.ApprovalRequest{
+			TeamID:    team.ID,
+			CompanyID: company.ID,
+			ContestID: *team.ContestID,
+			Status:    models.ApprovalApproved,
+			Reason:    "assigned by admin",
+			DecidedAt: &now,
+		}
+		capacity = company.Capacity
+		team.CompanyID = &company.ID
+	}
+	// The current task belongs to the previous company, so it is dropped
+	team.CurrentTaskID = nil
+
+	err = h.teams.ReassignTeam(team, request, capacity)
+	if errors.Is(err, repository.ErrCapacityReached) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Company capacity reached"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+func (h *TeamAdminHandler) UnlinkTelegram(c *gin.Context) {
+	team, ok := h.findTeam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.teamService.UnlinkTelegram(team.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.audit.Record(c, "team.unlink_telegram", "team", team.ID, nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"status": "telegram_unlinked"})
+}
+
+func (h *TeamAdminHandler) ResetPassword(c *gin.Context) {
+	team, ok := h.findTeam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.teamService.ResetTeamPassword(team.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.audit.Record(c, "team.reset_password", "team", team.ID, nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "password_reset",
+		"message": "New password sent to team email",
+	})
+}
+
+func (h *TeamAdminHandler) DisqualifyTeam(c *gin.Context) {
+	team, ok := h.findTeam(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	// The reason is optional, an empty body is accepted
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	before := teamResponse(team)
+	team, err := h.teamService.DisqualifyTeam(team.ID, input.Reason)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.audit.Record(c, "team.disqualify", "team", team.ID, before, teamResponse(team))
+
+	c.JSON(http.StatusOK, gin.H{"status": team.Status, "team": teamResponse(team)})
+}
+
+func (h *TeamAdminHandler) ReinstateTeam(c *gin.Context) {
+	team, ok := h.findTeam(c)
+	if !ok {
+		return
+	}
+
+	before := teamResponse(team)
+	team, err := h.teamService.ReinstateTeam(team.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.audit.Record(c, "team.reinstate", "team", team.ID, before, teamResponse(team))
+
+	c.JSON(http.StatusOK, gin.H{"status": team.Status, "team": teamResponse(team)})
+}
+
+func (h *TeamAdminHandler) GetAdjustments(c *gin.Context) {
+	team, ok := h.findTeam(c)
+	if !ok {
+		return
+	}
+
+	adjustments, err := h.teamService.GetAdjustments(team.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := make([]gin.H, 0, len(adjustments))
+	for i := range adjustments {
+		response = append(response, adjustmentResponse(&adjustments[i]))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *TeamAdminHandler) AddAdjustment(c *gin.Context) {
+	team, ok := h.findTeam(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		DeltaPoints int `json:"delta_points"`
+		// Duration in Go format, e.g. "5m" or "-90s"
+		DeltaDuration string `json:"delta_duration"`
+		Reason        string `json:"reason" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var deltaDuration time.Duration
+	if input.DeltaDuration != "" {
+		var err error
+		if deltaDuration, err = time.ParseDuration(input.DeltaDuration); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delta_duration"})
+			return
+		}
+	}
+
+	if input.DeltaPoints == 0 && deltaDuration == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Adjustment must change points or duration"})
+		return
+	}
+
+	adjustment, err := h.teamService.AddAdjustment(team.ID, input.DeltaPoints, deltaDuration, input.Reason, pkg.AdminUsername(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.audit.Record(c, "adjustment.add", "adjustment", adjustment.ID, nil, adjustmentResponse(adjustment))
+
+	c.JSON(http.StatusCreated, adjustmentResponse(adjustment))
+}
+
+func (h *TeamAdminHandler) RevokeAdjustment(c *gin.Context) {
+	team, ok := h.findTeam(c)
+	if !ok {
+		return
+	}
+
+	adjustmentID, err := uuid.Parse(c.Param("adjustmentID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid adjustment ID"})
+		return
+	}
+
+	adjustment, err := h.teamService.RevokeAdjustment(team.ID, adjustmentID, pkg.AdminUsername(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.audit.Record(c, "adjustment.revoke", "adjustment", adjustment.ID, nil, adjustmentResponse(adjustment))
+
+	c.JSON(http.StatusOK, gin.H{"status": "revoked", "adjustment": adjustmentResponse(adjustment)})
+}
+
+func (h *TeamAdminHandler) GetLeaderboard(c *gin.Context) {
+	contestID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	leaderboard, err := h.teamService.GetLeaderboard(contestID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, leaderboard)
+}
+
+func (h *TeamAdminHandler) SendContestReminder(c *gin.Context) {
+	contestID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	queued, err := h.teamService.SendContestReminder(contestID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.audit.Record(c, "contest.send_reminder", "contest", contestID, nil, gin.H{"queued": queued})
+
+	c.JSON(http.StatusOK, gin.H{"status": "queued", "queued": queued})
+}
+
+func (h *TeamAdminHandler) SendContestResults(c *gin.Context) {
+	contestID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	queued, err := h.teamService.SendContestResults(contestID)
+	if err != nil {
+		if errors.Is(err, service.ErrContestNotCompleted) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Contest is not completed"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.audit.Record(c, "contest.send_results", "contest", contestID, nil, gin.H{"queued": queued})
+
+	c.JSON(http.StatusOK, gin.H{"status": "queued", "queued": queued})
+}
+
+func (h *TeamAdminHandler) GetFlaggedSessions(c *gin.Context) {
+	var filter repository.FlagFilter
+	var err error
+
+	if filter.ContestID, err = parseOptionalUUID(c.Query("contest_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contest ID"})
+		return
+	}
+	if filter.TeamID, err = parseOptionalUUID(c.Query("team_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid team ID"})
+		return
+	}
+	filter.Kind = c.Query("kind")
+
+	sessions, err := h.teamService.GetFlaggedSessions(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+func adjustmentResponse(adjustment *models.TeamAdjustment) gin.H {
+	return gin.H{
+		"id":             adjustment.ID,
+		"team_id":        adjustment.TeamID,
+		"delta_points":   adjustment.DeltaPoints,
+		"delta_duration": adjustment.DeltaDuration.String(),
+		"reason":         adjustment.Reason,
+		"author":         adjustment.Author,
+		"revoked":        adjustment.RevokedAt != nil,
+		"revoked_at":     adjustment.RevokedAt,
+		"revoked_by":     adjustment.RevokedBy,
+		"created_at":     adjustment.CreatedAt,
+	}
+}
+
+func (h *TeamAdminHandler) DeleteTeam(c *gin.Context) {
+	team, ok := h.findTeam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.teams.Delete(team.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.audit.Record(c, "team.delete", "team", team.ID, teamResponse(team), nil)
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// findTeam loads the team from the :id path parameter and writes an error response if it fails
+func (h *TeamAdminHandler) findTeam(c *gin.Context) (*models.Team, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return nil, false
+	}
+
+	team, err := h.teams.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return nil, false
+	}
+	return team, true
+}
@@ -0,0 +1,170 @@
+package company
+
+import (
+	"Cyber-chase/internal/models"
+	"Cyber-chase/internal/pkg/mail"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// defaultTempPasswordTTL how long an emailed temporary password and setup link stay valid
+const defaultTempPasswordTTL = 72 * time.Hour
+
+func tempPasswordTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("COMPANY_TEMP_PASSWORD_TTL"))
+	if err != nil || ttl <= 0 {
+		return defaultTempPasswordTTL
+	}
+	return ttl
+}
+
+// setupURL builds the one-time link from COMPANY_SETUP_URL, the token goes into the query string
+func setupURL(token string) string {
+	base := os.Getenv("COMPANY_SETUP_URL")
+	if base == "" {
+		base = "http://localhost:3000/company/setup"
+	}
+	return base + "?token=" + url.QueryEscape(token)
+}
+
+// hashToken returns the SHA-256 hex of a one-time secret (setup tokens, staff invite codes),
+// only this hash is stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueTempCredentials gives the company a fresh hashed temporary password and
+// a one-time setup token, both expiring together. Only the plaintext values are
+// returned, the company keeps hashes and has to change the password on login.
+func issueTempCredentials(company *models.Company) (password, token string, err error) {
+	password, err = generateTempPassword(12)
+	if err != nil {
+		return "", "", err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", "", err
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(raw)
+
+	expiresAt := time.Now().Add(tempPasswordTTL())
+	company.PasswordHash = string(hash)
+	company.ResetRequired = true
+	company.PasswordExpiresAt = &expiresAt
+	company.SetupTokenHash = hashToken(token)
+	company.SetupTokenExpiresAt = &expiresAt
+	return password, token, nil
+}
+
+// setPermanentPassword stores a password chosen by the company and retires every temporary credential
+func setPermanentPassword(company *models.Company, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	company.PasswordHash = string(hash)
+	company.ResetRequired = false
+	company.PasswordExpiresAt = nil
+	company.SetupTokenHash = ""
+	company.SetupTokenExpiresAt = nil
+	// Previously issued tokens stop working after a password change
+	company.TokenVersion++
+	return nil
+}
+
+// tempPasswordExpired reports whether the company is still on a temporary password that has run out
+func tempPasswordExpired(company *models.Company) bool {
+	return company.ResetRequired && company.PasswordExpiresAt != nil && time.Now().After(*company.PasswordExpiresAt)
+}
+
+func welcomeEmail(company *models.Company, password, token string) *models.OutboxEmail {
+	return mail.CompanyWelcome(company.Email, company.Language, company.Name, password, setupURL(token), *company.PasswordExpiresAt)
+}
+
+func passwordResetEmail(company *models.Company, password, token string) *models.OutboxEmail {
+	return mail.CompanyPasswordReset(company.Email, company.Language, company.Name, password, setupURL(token), *company.PasswordExpiresAt)
+}
+
+// ReissueLegacyCredentials gives companies created before passwords were hashed fresh
+// temporary credentials. Their password_hash is empty, so they could never log in;
+// now they get the usual reset email and have to set a password like a new company.
+// Runs at startup, companies that already have a password are left alone.
+func ReissueLegacyCredentials(ctx context.Context, store CompanyStore) error {
+	companies, err := store.GetCompaniesWithoutPassword(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range companies {
+		company := &companies[i]
+		password, token, err := issueTempCredentials(company)
+		if err != nil {
+			return err
+		}
+		company.TokenVersion++
+
+		if err := store.UpdateCompanyWithEmail(ctx, company, passwordResetEmail(company, password, token)); err != nil {
+			return err
+		}
+		log.Printf("Company %q had no password, temporary credentials were emailed to %s", company.Name, company.Email)
+	}
+	return nil
+}
+
+// SetupAccount sets the company password through the one-time link from the email
+func (h *CompanyHandler) SetupAccount(c *gin.Context) {
+	var input struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required,min=8"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	company, err := h.repo.GetCompanyBySetupToken(c.Request.Context(), hashToken(input.Token))
+	if err != nil || company.SetupTokenExpiresAt == nil || time.Now().After(*company.SetupTokenExpiresAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired setup link"})
+		return
+	}
+
+	if err := setPermanentPassword(company, input.Password); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password"})
+		return
+	}
+
+	if err := h.repo.UpdateCompany(c.Request.Context(), company); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		return
+	}
+
+	pair, err := h.tokens.IssuePair(c.Request.Context(), companyClaims(company))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	response := pair.Fields()
+	response["status"] = "password_set"
+	response["role"] = models.CompanyRoleOwner
+	c.JSON(http.StatusOK, response)
+}
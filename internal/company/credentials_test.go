@@ -0,0 +1,406 @@
+package company
+
+import (
+	"Cyber-chase/internal/models"
+	"Cyber-chase/internal/pkg"
+	"Cyber-chase/internal/pkg/audit"
+	"Cyber-chase/internal/pkg/auth"
+	"Cyber-chase/internal/pkg/mail"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var errNotFound = errors.New("record not found")
+
+// fakeStore keeps companies and queued emails in memory. It also serves token
+// versions and audit entries so the handler runs without a database.
+type fakeStore struct {
+	companies map[uuid.UUID]models.Company
+	users     []models.CompanyUser
+	emails    []models.OutboxEmail
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{companies: map[uuid.UUID]models.Company{}}
+}
+
+func (s *fakeStore) CreateCompanyWithEmail(_ context.Context, company *models.Company, email *models.OutboxEmail) error {
+	for _, existing := range s.companies {
+		if existing.Email == company.Email || existing.Name == company.Name {
+			return errors.New("duplicate key")
+		}
+	}
+	company.ID = uuid.New()
+	s.companies[company.ID] = *company
+	s.emails = append(s.emails, *email)
+	return nil
+}
+
+func (s *fakeStore) UpdateCompanyWithEmail(_ context.Context, company *models.Company, email *models.OutboxEmail) error {
+	s.companies[company.ID] = *company
+	s.emails = append(s.emails, *email)
+	return nil
+}
+
+func (s *fakeStore) GetAllCompanies(context.Context) ([]models.Company, error) {
+	companies := make([]models.Company, 0, len(s.companies))
+	for _, company := range s.companies {
+		companies = append(companies, company)
+	}
+	return companies, nil
+}
+
+func (s *fakeStore) GetCompaniesWithoutPassword(context.Context) ([]models.Company, error) {
+	var companies []models.Company
+	for _, company := range s.companies {
+		if company.PasswordHash == "" {
+			companies = append(companies, company)
+		}
+	}
+	return companies, nil
+}
+
+func (s *fakeStore) GetCompanyByID(_ context.Context, id uuid.UUID) (*models.Company, error) {
+	company, ok := s.companies[id]
+	if !ok {
+		return nil, errNotFound
+	}
+	return &company, nil
+}
+
+func (s *fakeStore) GetCompanyByEmail(_ context.Context, email string) (*models.Company, error) {
+	for _, company := range s.companies {
+		if company.Email == email {
+			return &company, nil
+		}
+	}
+	return nil, errNotFound
+}
+
+func (s *fakeStore) GetCompanyBySetupToken(_ context.Context, tokenHash string) (*models.Company, error) {
+	for _, company := range s.companies {
+		if company.SetupTokenHash != "" && company.SetupTokenHash == tokenHash {
+			return &company, nil
+		}
+	}
+	return nil, errNotFound
+}
+
+func (s *fakeStore) UpdateCompany(_ context.Context, company *models.Company) error {
+	s.companies[company.ID] = *company
+	return nil
+}
+
+func (s *fakeStore) DeleteCompany(_ context.Context, id uuid.UUID) error {
+	delete(s.companies, id)
+	return nil
+}
+
+func (s *fakeStore) CreateCompanyUserWithEmail(context.Context, *models.CompanyUser, *models.OutboxEmail) error {
+	return errors.New("not supported")
+}
+
+func (s *fakeStore) GetCompanyUsers(context.Context, uuid.UUID) ([]models.CompanyUser, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) GetCompanyUserByID(context.Context, uuid.UUID) (*models.CompanyUser, error) {
+	return nil, errNotFound
+}
+
+func (s *fakeStore) GetCompanyUserByEmail(_ context.Context, email string) (*models.CompanyUser, error) {
+	for _, user := range s.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, errNotFound
+}
+
+func (s *fakeStore) GetCompanyUserByInviteCode(context.Context, string) (*models.CompanyUser, error) {
+	return nil, errNotFound
+}
+
+func (s *fakeStore) UpdateCompanyUser(context.Context, *models.CompanyUser) error {
+	return errors.New("not supported")
+}
+
+func (s *fakeStore) DeleteCompanyUser(context.Context, uuid.UUID) error {
+	return errors.New("not supported")
+}
+
+func (s *fakeStore) TokenVersion(_ context.Context, role, subjectID, userID string) (int, error) {
+	id, err := uuid.Parse(subjectID)
+	if err != nil || role != auth.RoleCompany || userID != "" {
+		return 0, errNotFound
+	}
+	company, ok := s.companies[id]
+	if !ok {
+		return 0, errNotFound
+	}
+	return company.TokenVersion, nil
+}
+
+func (s *fakeStore) SaveRefreshToken(context.Context, *models.RefreshToken) error {
+	return nil
+}
+
+func (s *fakeStore) FindRefreshToken(context.Context, string) (*models.RefreshToken, error) {
+	return nil, errNotFound
+}
+
+func (s *fakeStore) RevokeRefreshToken(context.Context, uuid.UUID) (bool, error) {
+	return true, nil
+}
+
+func (s *fakeStore) CreateAuditLog(context.Context, *models.AuditLog) error {
+	return nil
+}
+
+func (s *fakeStore) FindAuditLogs(context.Context, audit.Filter) ([]models.AuditLog, error) {
+	return nil, nil
+}
+
+// lastEmail returns the template data of the most recently queued email
+func (s *fakeStore) lastEmail(t *testing.T, kind string) map[string]string {
+	t.Helper()
+	if len(s.emails) == 0 {
+		t.Fatal("no email was queued")
+	}
+	email := s.emails[len(s.emails)-1]
+	if email.Kind != kind {
+		t.Fatalf("email kind = %s, want %s", email.Kind, kind)
+	}
+	var data map[string]string
+	if err := json.Unmarshal([]byte(email.Data), &data); err != nil {
+		t.Fatalf("decode email data: %v", err)
+	}
+	return data
+}
+
+const locationPath = "/api/v1/company/location"
+
+func newTestRouter(store *fakeStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	tokens := auth.NewManager("test-secret", time.Minute, time.Hour, store)
+	h := NewCompanyHandler(store, nil, tokens, audit.NewRecorder(store))
+
+	router := gin.New()
+	router.POST("/api/v1/admin/companies", h.CreateCompany)
+	router.POST("/api/v1/company/login", h.CompanyLogin)
+	router.POST("/api/v1/company/setup", h.SetupAccount)
+
+	companyRoutes := router.Group("/api/v1/company")
+	companyRoutes.Use(pkg.Authorize(tokens, pkg.CompanyPolicy))
+	companyRoutes.POST("/change-password", h.ChangePassword)
+	companyRoutes.GET("/location", h.GetMapLink)
+	return router
+}
+
+func call(t *testing.T, router *gin.Engine, method, path, token string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatalf("encode body: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(method, path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var response map[string]interface{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &response)
+	return rec.Code, response
+}
+
+func login(t *testing.T, router *gin.Engine, email, password string) (int, map[string]interface{}) {
+	t.Helper()
+	return call(t, router, http.MethodPost, "/api/v1/company/login", "", gin.H{"email": email, "password": password})
+}
+
+// createCompany creates a company through the admin handler and returns the emailed credentials
+func createCompany(t *testing.T, router *gin.Engine, store *fakeStore, email string) map[string]string {
+	t.Helper()
+	status, body := call(t, router, http.MethodPost, "/api/v1/admin/companies", "", gin.H{
+		"name":     "Company " + email,
+		"email":    email,
+		"location": "https://maps.example.com/office",
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("create status = %d, body %v", status, body)
+	}
+	return store.lastEmail(t, mail.KindWelcome)
+}
+
+func setupToken(t *testing.T, credentials map[string]string) string {
+	t.Helper()
+	link, err := url.Parse(credentials["SetupURL"])
+	if err != nil {
+		t.Fatalf("parse setup url: %v", err)
+	}
+	token := link.Query().Get("token")
+	if token == "" {
+		t.Fatalf("setup url %s has no token", credentials["SetupURL"])
+	}
+	return token
+}
+
+func TestCompanyOnboarding(t *testing.T) {
+	store := newFakeStore()
+	router := newTestRouter(store)
+
+	credentials := createCompany(t, router, store, "owner@example.com")
+	tempPassword := credentials["Password"]
+	if tempPassword == "" {
+		t.Fatal("welcome email has no temporary password")
+	}
+	for _, company := range store.companies {
+		if company.PasswordHash == tempPassword {
+			t.Fatal("temporary password is stored in plaintext")
+		}
+	}
+
+	status, body := login(t, router, "owner@example.com", tempPassword)
+	if status != http.StatusOK {
+		t.Fatalf("login status = %d, body %v", status, body)
+	}
+	if body["reset_required"] != true {
+		t.Fatalf("reset_required = %v, want true", body["reset_required"])
+	}
+	tempToken := body["token"].(string)
+
+	status, body = call(t, router, http.MethodGet, locationPath, tempToken, nil)
+	if status != http.StatusForbidden || body["code"] != "PASSWORD_RESET_REQUIRED" {
+		t.Fatalf("location before change = %d %v, want 403 PASSWORD_RESET_REQUIRED", status, body)
+	}
+
+	status, body = call(t, router, http.MethodPost, "/api/v1/company/change-password", tempToken, gin.H{
+		"old_password": tempPassword,
+		"new_password": "a-new-password",
+	})
+	if status != http.StatusOK {
+		t.Fatalf("change password status = %d, body %v", status, body)
+	}
+	newToken := body["token"].(string)
+
+	if status, _ := login(t, router, "owner@example.com", tempPassword); status != http.StatusUnauthorized {
+		t.Fatalf("login with old password = %d, want 401", status)
+	}
+	if status, _ := call(t, router, http.MethodGet, locationPath, tempToken, nil); status != http.StatusUnauthorized {
+		t.Fatalf("old token after change = %d, want 401", status)
+	}
+	if status, body := call(t, router, http.MethodGet, locationPath, newToken, nil); status != http.StatusOK {
+		t.Fatalf("new token after change = %d %v, want 200", status, body)
+	}
+
+	status, body = login(t, router, "owner@example.com", "a-new-password")
+	if status != http.StatusOK || body["reset_required"] != false {
+		t.Fatalf("login with new password = %d %v, want 200 without reset", status, body)
+	}
+}
+
+func TestExpiredTempPassword(t *testing.T) {
+	store := newFakeStore()
+	router := newTestRouter(store)
+
+	credentials := createCompany(t, router, store, "late@example.com")
+	for id, company := range store.companies {
+		expired := time.Now().Add(-time.Minute)
+		company.PasswordExpiresAt = &expired
+		store.companies[id] = company
+	}
+
+	status, body := login(t, router, "late@example.com", credentials["Password"])
+	if status != http.StatusUnauthorized || body["code"] != "TEMP_PASSWORD_EXPIRED" {
+		t.Fatalf("login = %d %v, want 401 TEMP_PASSWORD_EXPIRED", status, body)
+	}
+}
+
+func TestSetupTokenIsOneTime(t *testing.T) {
+	store := newFakeStore()
+	router := newTestRouter(store)
+
+	credentials := createCompany(t, router, store, "setup@example.com")
+	token := setupToken(t, credentials)
+
+	status, body := call(t, router, http.MethodPost, "/api/v1/company/setup", "", gin.H{"token": token, "password": "first-password"})
+	if status != http.StatusOK {
+		t.Fatalf("setup status = %d, body %v", status, body)
+	}
+
+	status, _ = call(t, router, http.MethodPost, "/api/v1/company/setup", "", gin.H{"token": token, "password": "second-password"})
+	if status != http.StatusBadRequest {
+		t.Fatalf("second setup = %d, want 400", status)
+	}
+	if status, _ := login(t, router, "setup@example.com", credentials["Password"]); status != http.StatusUnauthorized {
+		t.Fatalf("login with temporary password after setup = %d, want 401", status)
+	}
+	if status, _ := login(t, router, "setup@example.com", "first-password"); status != http.StatusOK {
+		t.Fatalf("login with password from setup = %d, want 200", status)
+	}
+}
+
+func TestReissueLegacyCredentials(t *testing.T) {
+	store := newFakeStore()
+	router := newTestRouter(store)
+
+	legacy := models.Company{ID: uuid.New(), Name: "Legacy", Email: "legacy@example.com", TokenVersion: 3}
+	store.companies[legacy.ID] = legacy
+
+	if status, _ := login(t, router, legacy.Email, "any-password"); status != http.StatusUnauthorized {
+		t.Fatalf("legacy login = %d, want 401", status)
+	}
+
+	if err := ReissueLegacyCredentials(context.Background(), store); err != nil {
+		t.Fatalf("reissue: %v", err)
+	}
+	credentials := store.lastEmail(t, mail.KindPasswordReset)
+	if store.companies[legacy.ID].TokenVersion != legacy.TokenVersion+1 {
+		t.Fatal("token version was not bumped")
+	}
+
+	status, body := login(t, router, legacy.Email, credentials["Password"])
+	if status != http.StatusOK || body["reset_required"] != true {
+		t.Fatalf("login after reissue = %d %v, want 200 with reset", status, body)
+	}
+
+	sent := len(store.emails)
+	if err := ReissueLegacyCredentials(context.Background(), store); err != nil {
+		t.Fatalf("second reissue: %v", err)
+	}
+	if len(store.emails) != sent {
+		t.Fatal("companies with a password got new credentials")
+	}
+}
+
+func TestLoginRejectsPasswordHash(t *testing.T) {
+	store := newFakeStore()
+	router := newTestRouter(store)
+
+	createCompany(t, router, store, "hash@example.com")
+	company, err := store.GetCompanyByEmail(context.Background(), "hash@example.com")
+	if err != nil {
+		t.Fatalf("get company: %v", err)
+	}
+
+	status, body := login(t, router, company.Email, company.PasswordHash)
+	if status != http.StatusUnauthorized {
+		t.Fatalf("login with the stored hash = %d %v, want 401", status, body)
+	}
+}
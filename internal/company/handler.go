@@ -3,43 +3,69 @@ package company
 import (
 	"Cyber-chase/internal/models"
 	"Cyber-chase/internal/pkg"
+	"Cyber-chase/internal/pkg/audit"
+	"Cyber-chase/internal/pkg/auth"
+	"Cyber-chase/internal/pkg/mail"
 	"Cyber-chase/internal/repository"
 	"Cyber-chase/internal/service"
+	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 	"math/big"
 	"net/http"
 	"os"
 	"path/filepath"
-	"time"
 )
 
+// CompanyStore is the part of the repository used by CompanyHandler
+type CompanyStore interface {
+	CreateCompanyWithEmail(ctx context.Context, company *models.Company, email *models.OutboxEmail) error
+	UpdateCompanyWithEmail(ctx context.Context, company *models.Company, email *models.OutboxEmail) error
+	GetAllCompanies(ctx context.Context) ([]models.Company, error)
+	GetCompaniesWithoutPassword(ctx context.Context) ([]models.Company, error)
+	GetCompanyByID(ctx context.Context, id uuid.UUID) (*models.Company, error)
+	GetCompanyByEmail(ctx context.Context, email string) (*models.Company, error)
+	GetCompanyBySetupToken(ctx context.Context, tokenHash string) (*models.Company, error)
+	UpdateCompany(ctx context.Context, company *models.Company) error
+	DeleteCompany(ctx context.Context, id uuid.UUID) error
+
+	CreateCompanyUserWithEmail(ctx context.Context, user *models.CompanyUser, email *models.OutboxEmail) error
+	GetCompanyUsers(ctx context.Context, companyID uuid.UUID) ([]models.CompanyUser, error)
+	GetCompanyUserByID(ctx context.Context, id uuid.UUID) (*models.CompanyUser, error)
+	GetCompanyUserByEmail(ctx context.Context, email string) (*models.CompanyUser, error)
+	GetCompanyUserByInviteCode(ctx context.Context, codeHash string) (*models.CompanyUser, error)
+	UpdateCompanyUser(ctx context.Context, user *models.CompanyUser) error
+	DeleteCompanyUser(ctx context.Context, id uuid.UUID) error
+}
+
 type CompanyHandler struct {
-	repo        *repository.Repository
-	mailer      MailService
-	jwtSecret   string
+	repo        CompanyStore
+	tokens      *auth.Manager
 	teamService service.TeamService
+	audit       *audit.Recorder
 }
 
-func NewCompanyHandler(repo *repository.Repository, mailer MailService, teamService service.TeamService) *CompanyHandler {
+func NewCompanyHandler(repo CompanyStore, teamService service.TeamService, tokens *auth.Manager, recorder *audit.Recorder) *CompanyHandler {
 	return &CompanyHandler{
 		repo:        repo,
-		mailer:      mailer,
-		jwtSecret:   os.Getenv("JWT_SECRET"),
+		tokens:      tokens,
 		teamService: teamService,
+		audit:       recorder,
 	}
 }
 
 type CompanyTaskHandler struct {
-	repo *repository.Repository
+	repo        *repository.Repository
+	botUsername string
+	audit       *audit.Recorder
 }
 
-func NewCompanyTaskHandler(repo *repository.Repository) *CompanyTaskHandler {
-	return &CompanyTaskHandler{repo: repo}
+func NewCompanyTaskHandler(repo *repository.Repository, botUsername string, recorder *audit.Recorder) *CompanyTaskHandler {
+	return &CompanyTaskHandler{repo: repo, botUsername: botUsername, audit: recorder}
 }
 
 func generateTempPassword(length int) (string, error) {
@@ -57,11 +83,52 @@ func generateTempPassword(length int) (string, error) {
 	return string(result), nil
 }
 
+func isValidAnswerType(answerType string) bool {
+	switch answerType {
+	case models.AnswerTypeText, models.AnswerTypePhoto, models.AnswerTypeQR:
+		return true
+	}
+	return false
+}
+
+// prepareQRAnswer generates a code for QR tasks that have none and checks
+// that a provided one fits into a bot deep link
+func prepareQRAnswer(task *models.Task) error {
+	if task.AnswerType != models.AnswerTypeQR {
+		return nil
+	}
+	if task.CorrectAnswer == "" {
+		code, err := pkg.GenerateQRCode()
+		if err != nil {
+			return err
+		}
+		task.CorrectAnswer = code
+		return nil
+	}
+	if !pkg.IsValidQRCode(task.CorrectAnswer) {
+		return fmt.Errorf("QR answer may contain only latin letters, digits, '_' and '-' (up to 61 characters)")
+	}
+	return nil
+}
+
+func companyResponse(company *models.Company) gin.H {
+	return gin.H{
+		"id":           company.ID,
+		"name":         company.Name,
+		"email":        company.Email,
+		"reset_needed": company.ResetRequired,
+		"location":     company.Location,
+		"capacity":     company.Capacity,
+		"language":     company.Language,
+	}
+}
+
 func (h *CompanyHandler) CreateCompany(c *gin.Context) {
 	var input struct {
 		Name     string `json:"name" binding:"required"`
 		Email    string `json:"email" binding:"required,email"`
 		Location string `json:"location"`
+		Language string `json:"language"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -69,29 +136,29 @@ func (h *CompanyHandler) CreateCompany(c *gin.Context) {
 		return
 	}
 
-	tempPass, err := generateTempPassword(12)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate password"})
+	if h.isStaffEmail(c, input.Email) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already belongs to a staff account"})
 		return
 	}
 
 	company := &models.Company{
-		Name:          input.Name,
-		Email:         input.Email,
-		TempPassword:  tempPass,
-		ResetRequired: true,
-		Location:      input.Location,
+		Name:     input.Name,
+		Email:    input.Email,
+		Location: input.Location,
+		Language: mail.NormalizeLanguage(input.Language),
 	}
 
-	if err := h.repo.CreateCompany(c.Request.Context(), company); err != nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Company already exists"})
+	tempPass, setupToken, err := issueTempCredentials(company)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate password"})
 		return
 	}
 
-	if err := h.mailer.SendTempPassword(company.Email, tempPass); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send password"})
+	if err := h.repo.CreateCompanyWithEmail(c.Request.Context(), company, welcomeEmail(company, tempPass, setupToken)); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Company already exists"})
 		return
 	}
+	h.audit.Record(c, "company.create", "company", company.ID, nil, companyResponse(company))
 
 	c.JSON(http.StatusCreated, gin.H{
 		"id":     company.ID,
@@ -107,14 +174,8 @@ func (h *CompanyHandler) GetAllCompanies(c *gin.Context) {
 	}
 
 	response := make([]gin.H, 0)
-	for _, comp := range companies {
-		response = append(response, gin.H{
-			"id":           comp.ID,
-			"name":         comp.Name,
-			"email":        comp.Email,
-			"reset_needed": comp.ResetRequired,
-			"location":     comp.Location,
-		})
+	for i := range companies {
+		response = append(response, companyResponse(&companies[i]))
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -131,6 +192,7 @@ func (h *CompanyHandler) UpdateCompany(c *gin.Context) {
 		Name     string `json:"name"`
 		Email    string `json:"email"`
 		Location string `json:"location"`
+		Language string `json:"language"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -143,21 +205,30 @@ func (h *CompanyHandler) UpdateCompany(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
 		return
 	}
+	before := companyResponse(company)
 
 	if input.Name != "" {
 		company.Name = input.Name
 	}
 	if input.Email != "" {
+		if h.isStaffEmail(c, input.Email) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already belongs to a staff account"})
+			return
+		}
 		company.Email = input.Email
 	}
 	if input.Location != "" {
 		company.Location = input.Location
 	}
+	if input.Language != "" {
+		company.Language = mail.NormalizeLanguage(input.Language)
+	}
 
 	if err := h.repo.UpdateCompany(c.Request.Context(), company); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.audit.Record(c, "company.update", "company", company.ID, before, companyResponse(company))
 
 	c.JSON(http.StatusOK, gin.H{"status": "updated"})
 }
@@ -175,24 +246,19 @@ func (h *CompanyHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
-	newPass, err := generateTempPassword(12)
+	newPass, setupToken, err := issueTempCredentials(company)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate password"})
 		return
 	}
+	// The old password is gone, so are the tokens issued with it
+	company.TokenVersion++
 
-	company.TempPassword = newPass
-	company.ResetRequired = true
-
-	if err := h.mailer.SendTempPassword(company.Email, newPass); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send password"})
-		return
-	}
-
-	if err := h.repo.UpdateCompany(c.Request.Context(), company); err != nil {
+	if err := h.repo.UpdateCompanyWithEmail(c.Request.Context(), company, passwordResetEmail(company, newPass, setupToken)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.audit.Record(c, "company.reset_password", "company", company.ID, nil, nil)
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "password_reset",
@@ -207,10 +273,17 @@ func (h *CompanyHandler) DeleteCompany(c *gin.Context) {
 		return
 	}
 
+	company, err := h.repo.GetCompanyByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		return
+	}
+
 	if err := h.repo.DeleteCompany(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.audit.Record(c, "company.delete", "company", id, companyResponse(company), nil)
 
 	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
 }
@@ -228,7 +301,10 @@ func (h *CompanyHandler) CompanyLogin(c *gin.Context) {
 
 	company, err := h.repo.GetCompanyByEmail(c.Request.Context(), req.Email)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		// The email may belong to a staff account instead of the company itself
+		if !h.companyUserLogin(c, req.Email, req.Password) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		}
 		return
 	}
 
@@ -237,31 +313,28 @@ func (h *CompanyHandler) CompanyLogin(c *gin.Context) {
 		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":            company.ID.String(),
-		"role":           "company",
-		"exp":            time.Now().Add(time.Hour * 24).Unix(),
-		"reset_required": company.ResetRequired,
-	})
+	if tempPasswordExpired(company) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Temporary password expired, ask the administrator for a new one",
+			"code":  "TEMP_PASSWORD_EXPIRED",
+		})
+		return
+	}
 
-	tokenString, err := token.SignedString([]byte(h.jwtSecret))
+	pair, err := h.tokens.IssuePair(c.Request.Context(), companyClaims(company))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"token":          tokenString,
-		"reset_required": company.ResetRequired,
-	})
+	response := pair.Fields()
+	response["role"] = models.CompanyRoleOwner
+	response["reset_required"] = company.ResetRequired
+	c.JSON(http.StatusOK, response)
 }
 
 func (h *CompanyHandler) GetMapLink(c *gin.Context) {
-	companyID, err := uuid.Parse(c.GetString("companyID"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
-		return
-	}
+	companyID := pkg.CompanyID(c)
 
 	company, err := h.repo.GetCompanyByID(c.Request.Context(), companyID)
 	if err != nil {
@@ -274,7 +347,7 @@ func (h *CompanyHandler) GetMapLink(c *gin.Context) {
 		return
 	}
 
-	mapLink := fmt.Sprintf(company.Location)
+	mapLink := company.Location
 
 	c.JSON(http.StatusOK, gin.H{
 		"map_link": mapLink,
@@ -282,11 +355,7 @@ func (h *CompanyHandler) GetMapLink(c *gin.Context) {
 }
 
 func (h *CompanyHandler) ChangePassword(c *gin.Context) {
-	companyID, err := uuid.Parse(c.GetString("companyID"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
-		return
-	}
+	companyID := pkg.CompanyID(c)
 
 	var req struct {
 		OldPassword string `json:"old_password" binding:"required"`
@@ -298,6 +367,11 @@ func (h *CompanyHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
+	if userID := pkg.CompanyUserID(c); userID != uuid.Nil {
+		h.changeUserPassword(c, userID, req.OldPassword, req.NewPassword)
+		return
+	}
+
 	company, err := h.repo.GetCompanyByID(c.Request.Context(), companyID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
@@ -309,45 +383,30 @@ func (h *CompanyHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	newHash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
-	if err != nil {
+	if err := setPermanentPassword(company, req.NewPassword); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process new password"})
 		return
 	}
 
-	company.PasswordHash = string(newHash)
-	company.ResetRequired = false
-
 	if err := h.repo.UpdateCompany(c.Request.Context(), company); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
 		return
 	}
+	h.audit.Record(c, "company.change_password", "company", company.ID, nil, nil)
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":            company.ID.String(),
-		"role":           "company",
-		"exp":            time.Now().Add(time.Hour * 24).Unix(),
-		"reset_required": false,
-	})
-
-	tokenString, err := token.SignedString([]byte(h.jwtSecret))
+	pair, err := h.tokens.IssuePair(c.Request.Context(), companyClaims(company))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": "password_changed",
-		"token":  tokenString,
-	})
+	response := pair.Fields()
+	response["status"] = "password_changed"
+	c.JSON(http.StatusOK, response)
 }
 
 func (h *CompanyTaskHandler) CreateTask(c *gin.Context) {
-	companyID, err := uuid.Parse(c.GetString("companyID"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
-		return
-	}
+	companyID := pkg.CompanyID(c)
 
 	// Parse form
 	if err := c.Request.ParseMultipartForm(10 << 20); err != nil { // 10 MB limit
@@ -376,13 +435,20 @@ func (h *CompanyTaskHandler) CreateTask(c *gin.Context) {
 	question := c.PostForm("question")
 	correctAnswer := c.PostForm("correct_answer")
 	timeLimitStr := c.PostForm("time_limit")
+	answerType := c.DefaultPostForm("answer_type", models.AnswerTypeText)
 
 	if question == "" && c.Request.MultipartForm.File["question_file"] == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Either question text or question file is required"})
 		return
 	}
 
-	if correctAnswer == "" {
+	if !isValidAnswerType(answerType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid answer type"})
+		return
+	}
+
+	// Photo answers are reviewed manually and QR codes can be generated
+	if correctAnswer == "" && answerType == models.AnswerTypeText {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Correct answer is required"})
 		return
 	}
@@ -391,11 +457,23 @@ func (h *CompanyTaskHandler) CreateTask(c *gin.Context) {
 	task := &models.Task{
 		ID:            uuid.New(),
 		Question:      question,
+		AnswerType:    answerType,
 		CorrectAnswer: correctAnswer,
 		ContestID:     contestID,
 		CompanyID:     companyID,
 	}
 
+	for _, text := range c.PostFormArray("hints") {
+		if text != "" {
+			task.Hints = append(task.Hints, models.TaskHint{TaskID: task.ID, Position: len(task.Hints) + 1, Text: text})
+		}
+	}
+
+	if err := prepareQRAnswer(task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Set time limit if provided
 	if timeLimitStr != "" {
 		var timeLimit int
@@ -403,6 +481,15 @@ func (h *CompanyTaskHandler) CreateTask(c *gin.Context) {
 		task.TimeLimit = timeLimit
 	}
 
+	// Order and difficulty drive the task sequence for teams
+	if orderStr := c.PostForm("order"); orderStr != "" {
+		fmt.Sscanf(orderStr, "%d", &task.Order)
+	}
+	task.Difficulty = 1
+	if difficultyStr := c.PostForm("difficulty"); difficultyStr != "" {
+		fmt.Sscanf(difficultyStr, "%d", &task.Difficulty)
+	}
+
 	// Handle file upload if present
 	if files := c.Request.MultipartForm.File["question_file"]; len(files) > 0 {
 		file := files[0]
@@ -422,6 +509,7 @@ func (h *CompanyTaskHandler) CreateTask(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.audit.Record(c, "task.create", "task", task.ID, nil, task)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"id":     task.ID,
@@ -430,11 +518,7 @@ func (h *CompanyTaskHandler) CreateTask(c *gin.Context) {
 }
 
 func (h *CompanyTaskHandler) GetCompanyTasks(c *gin.Context) {
-	companyID, err := uuid.Parse(c.GetString("companyID"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
-		return
-	}
+	companyID := pkg.CompanyID(c)
 
 	tasks, err := h.repo.GetTasksByCompanyID(c.Request.Context(), companyID)
 	if err != nil {
@@ -453,11 +537,7 @@ func (h *CompanyTaskHandler) UpdateTask(c *gin.Context) {
 		return
 	}
 
-	companyID, err := uuid.Parse(c.GetString("companyID"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
-		return
-	}
+	companyID := pkg.CompanyID(c)
 
 	task, err := h.repo.GetTaskByID(c.Request.Context(), taskID)
 	if err != nil {
@@ -469,6 +549,10 @@ func (h *CompanyTaskHandler) UpdateTask(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to update this task"})
 		return
 	}
+	before := *task
+
+	// Hints are replaced only when the request lists them
+	var newHints *[]string
 
 	// Parse form for file upload
 	if c.ContentType() == "multipart/form-data" {
@@ -480,6 +564,7 @@ func (h *CompanyTaskHandler) UpdateTask(c *gin.Context) {
 		question := c.PostForm("question")
 		correctAnswer := c.PostForm("correct_answer")
 		timeLimitStr := c.PostForm("time_limit")
+		answerType := c.PostForm("answer_type")
 
 		if question != "" {
 			task.Question = question
@@ -487,11 +572,27 @@ func (h *CompanyTaskHandler) UpdateTask(c *gin.Context) {
 		if correctAnswer != "" {
 			task.CorrectAnswer = correctAnswer
 		}
+		if answerType != "" {
+			if !isValidAnswerType(answerType) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid answer type"})
+				return
+			}
+			task.AnswerType = answerType
+		}
+		if hints, ok := c.GetPostFormArray("hints"); ok {
+			newHints = &hints
+		}
 		if timeLimitStr != "" {
 			var timeLimit int
 			fmt.Sscanf(timeLimitStr, "%d", &timeLimit)
 			task.TimeLimit = timeLimit
 		}
+		if orderStr := c.PostForm("order"); orderStr != "" {
+			fmt.Sscanf(orderStr, "%d", &task.Order)
+		}
+		if difficultyStr := c.PostForm("difficulty"); difficultyStr != "" {
+			fmt.Sscanf(difficultyStr, "%d", &task.Difficulty)
+		}
 
 		// Handle file update if present
 		if files := c.Request.MultipartForm.File["question_file"]; len(files) > 0 {
@@ -511,9 +612,13 @@ func (h *CompanyTaskHandler) UpdateTask(c *gin.Context) {
 		}
 	} else {
 		var input struct {
-			Question      string `json:"question"`
-			CorrectAnswer string `json:"correct_answer"`
-			TimeLimit     *int   `json:"time_limit"`
+			Question      string    `json:"question"`
+			AnswerType    string    `json:"answer_type"`
+			CorrectAnswer string    `json:"correct_answer"`
+			TimeLimit     *int      `json:"time_limit"`
+			Order         *int      `json:"order"`
+			Difficulty    *int      `json:"difficulty"`
+			Hints         *[]string `json:"hints"`
 		}
 
 		if err := c.ShouldBindJSON(&input); err != nil {
@@ -524,12 +629,37 @@ func (h *CompanyTaskHandler) UpdateTask(c *gin.Context) {
 		if input.Question != "" {
 			task.Question = input.Question
 		}
+		if input.AnswerType != "" {
+			if !isValidAnswerType(input.AnswerType) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid answer type"})
+				return
+			}
+			task.AnswerType = input.AnswerType
+		}
 		if input.CorrectAnswer != "" {
 			task.CorrectAnswer = input.CorrectAnswer
 		}
 		if input.TimeLimit != nil {
 			task.TimeLimit = *input.TimeLimit
 		}
+		if input.Order != nil {
+			task.Order = *input.Order
+		}
+		if input.Difficulty != nil {
+			task.Difficulty = *input.Difficulty
+		}
+		newHints = input.Hints
+	}
+
+	// A task switched to text answers must end up with an answer to check against
+	if task.AnswerType == models.AnswerTypeText && task.CorrectAnswer == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Correct answer is required"})
+		return
+	}
+
+	if err := prepareQRAnswer(task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
 	if err := h.repo.UpdateTask(c.Request.Context(), task); err != nil {
@@ -537,6 +667,22 @@ func (h *CompanyTaskHandler) UpdateTask(c *gin.Context) {
 		return
 	}
 
+	if newHints != nil {
+		texts := make([]string, 0, len(*newHints))
+		for _, text := range *newHints {
+			if text != "" {
+				texts = append(texts, text)
+			}
+		}
+		hints, err := h.repo.ReplaceTaskHints(c.Request.Context(), task.ID, texts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		task.Hints = hints
+	}
+	h.audit.Record(c, "task.update", "task", task.ID, before, task)
+
 	c.JSON(http.StatusOK, gin.H{"status": "updated", "task": task})
 }
 
@@ -553,8 +699,7 @@ func (h *CompanyTaskHandler) DeleteTask(c *gin.Context) {
 		return
 	}
 
-	companyID, err := uuid.Parse(c.GetString("companyID"))
-	if err != nil || task.CompanyID != companyID {
+	if task.CompanyID != pkg.CompanyID(c) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to delete this task"})
 		return
 	}
@@ -571,6 +716,7 @@ func (h *CompanyTaskHandler) DeleteTask(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.audit.Record(c, "task.delete", "task", taskID, task, nil)
 
 	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
 }
@@ -605,81 +751,271 @@ func (h *CompanyTaskHandler) GetTaskFile(c *gin.Context) {
 	c.FileAttachment(filePath, task.QuestionFile)
 }
 
-func (h *CompanyHandler) GetUnassignedTeams(c *gin.Context) {
-	teams, err := h.teamService.GetUnassignedTeams()
+func (h *CompanyTaskHandler) GetTaskQRCode(c *gin.Context) {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	companyID := pkg.CompanyID(c)
+
+	task, err := h.repo.GetTaskByID(c.Request.Context(), taskID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	if task.CompanyID != companyID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to access this task"})
+		return
+	}
+
+	if task.AnswerType != models.AnswerTypeQR {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Task is not a QR-code task"})
+		return
+	}
+
+	if h.botUsername == "" {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Bot username is not configured"})
+		return
+	}
+
+	link := pkg.QRDeepLink(h.botUsername, task.CorrectAnswer)
+
+	switch c.DefaultQuery("format", "png") {
+	case "png":
+		png, err := pkg.QRCodePNG(link, 512)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"task-%s.png\"", task.ID))
+		c.Data(http.StatusOK, "image/png", png)
+	case "pdf":
+		caption := fmt.Sprintf("Task %s", task.ID)
+		pdf, err := pkg.QRCodePDF(link, caption)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"task-%s.pdf\"", task.ID))
+		c.Data(http.StatusOK, "application/pdf", pdf)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be png or pdf"})
+	}
+}
+
+func (h *CompanyHandler) GetTeamRequests(c *gin.Context) {
+	companyID := pkg.CompanyID(c)
+
+	status := c.DefaultQuery("status", models.ApprovalPending)
+	if status == "all" {
+		status = ""
+	}
+
+	requests, err := h.teamService.GetApprovalRequests(companyID, status)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, teams)
+	c.JSON(http.StatusOK, requests)
 }
 
 func (h *CompanyHandler) ApproveTeam(c *gin.Context) {
-	companyID, err := uuid.Parse(c.GetString("companyID"))
+	companyID := pkg.CompanyID(c)
+
+	teamID, err := uuid.Parse(c.Param("teamID"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid team ID"})
+		return
+	}
+
+	if err := h.teamService.ApproveTeam(teamID, companyID); err != nil {
+		switch {
+		case errors.Is(err, service.ErrApprovalNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "No pending request from this team"})
+		case errors.Is(err, service.ErrCapacityReached):
+			c.JSON(http.StatusConflict, gin.H{"error": "Company capacity reached"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
+	h.audit.Record(c, "team.approve", "team", teamID, nil, gin.H{"company_id": companyID, "status": models.ApprovalApproved})
+
+	c.JSON(http.StatusOK, gin.H{"status": "team approved"})
+}
+
+func (h *CompanyHandler) RejectTeam(c *gin.Context) {
+	companyID := pkg.CompanyID(c)
+
 	teamID, err := uuid.Parse(c.Param("teamID"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid team ID"})
 		return
 	}
 
-	if err := h.teamService.ApproveTeam(teamID, companyID); err != nil {
+	var input struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.teamService.RejectTeam(teamID, companyID, input.Reason); err != nil {
+		if errors.Is(err, service.ErrApprovalNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No pending request from this team"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "team approved"})
+	h.audit.Record(c, "team.reject", "team", teamID, nil, gin.H{"company_id": companyID, "status": models.ApprovalRejected, "reason": input.Reason})
+
+	c.JSON(http.StatusOK, gin.H{"status": "team rejected"})
 }
 
-func (h *CompanyHandler) CompanyHashLogin(c *gin.Context) {
-	var req struct {
-		Email        string `json:"email" binding:"required"`
-		PasswordHash string `json:"password_hash" binding:"required"`
-		DirectAuth   bool   `json:"direct_auth" binding:"required"`
+func (h *CompanyHandler) GetDashboard(c *gin.Context) {
+	companyID := pkg.CompanyID(c)
+
+	dashboard, err := h.teamService.GetCompanyDashboard(companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
+	c.JSON(http.StatusOK, dashboard)
+}
+
+func (h *CompanyHandler) UpdateCapacity(c *gin.Context) {
+	companyID := pkg.CompanyID(c)
+
+	var input struct {
+		// 0 removes the limit
+		Capacity *int `json:"capacity" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if *input.Capacity < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Capacity must not be negative"})
+		return
+	}
 
-	// Only allow this endpoint to be used internally, never from public API
-	if !req.DirectAuth {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized access method"})
+	company, err := h.repo.GetCompanyByID(c.Request.Context(), companyID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
 		return
 	}
 
-	company, err := h.repo.GetCompanyByEmail(c.Request.Context(), req.Email)
+	before := companyResponse(company)
+	company.Capacity = *input.Capacity
+	if err := h.repo.UpdateCompany(c.Request.Context(), company); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.audit.Record(c, "company.update_capacity", "company", company.ID, before, companyResponse(company))
+
+	c.JSON(http.StatusOK, gin.H{"status": "updated", "capacity": company.Capacity})
+}
+
+func (h *CompanyHandler) GetSubmissions(c *gin.Context) {
+	companyID := pkg.CompanyID(c)
+
+	status := c.DefaultQuery("status", models.SubmissionPending)
+	if status == "all" {
+		status = ""
+	}
+
+	submissions, err := h.teamService.GetSubmissions(companyID, status)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Directly compare the password hash instead of using bcrypt.Compare
-	if company.PasswordHash != req.PasswordHash {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	c.JSON(http.StatusOK, submissions)
+}
+
+func (h *CompanyHandler) GetSubmissionFile(c *gin.Context) {
+	companyID := pkg.CompanyID(c)
+
+	submissionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid submission ID"})
 		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":            company.ID.String(),
-		"role":           "company",
-		"exp":            time.Now().Add(time.Hour * 24).Unix(),
-		"reset_required": company.ResetRequired,
-	})
+	submission, err := h.teamService.GetSubmission(companyID, submissionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return
+	}
+
+	filePath := pkg.GetSubmissionFilePath(submission.ID, submission.FileName)
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	c.FileAttachment(filePath, submission.FileName)
+}
+
+func (h *CompanyHandler) AcceptSubmission(c *gin.Context) {
+	h.reviewSubmission(c, true)
+}
+
+func (h *CompanyHandler) RejectSubmission(c *gin.Context) {
+	h.reviewSubmission(c, false)
+}
+
+func (h *CompanyHandler) reviewSubmission(c *gin.Context, accepted bool) {
+	companyID := pkg.CompanyID(c)
 
-	tokenString, err := token.SignedString([]byte(h.jwtSecret))
+	submissionID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid submission ID"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"token":          tokenString,
-		"reset_required": company.ResetRequired,
-	})
+	var input struct {
+		Comment string `json:"comment"`
+	}
+	// The comment is optional, so an empty body is fine
+	_ = c.ShouldBindJSON(&input)
+
+	submission, err := h.teamService.ReviewSubmission(companyID, submissionID, accepted, input.Comment)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	action := "submission.reject"
+	if accepted {
+		action = "submission.accept"
+	}
+	h.audit.Record(c, action, "submission", submission.ID, nil, submission)
+
+	c.JSON(http.StatusOK, gin.H{"status": submission.Status, "submission": submission})
+}
+
+func (h *CompanyHandler) GetTaskResults(c *gin.Context) {
+	companyID := pkg.CompanyID(c)
+
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	results, err := h.teamService.GetTaskResults(companyID, taskID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
 }
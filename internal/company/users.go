@@ -0,0 +1,336 @@
+package company
+
+import (
+	"Cyber-chase/internal/models"
+	"Cyber-chase/internal/pkg"
+	"Cyber-chase/internal/pkg/auth"
+	"Cyber-chase/internal/pkg/mail"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// companyInviteTTL how long a staff invite code stays valid
+const companyInviteTTL = 7 * 24 * time.Hour
+
+func isValidCompanyRole(role string) bool {
+	switch role {
+	case models.CompanyRoleOwner, models.CompanyRoleOperator, models.CompanyRoleViewer:
+		return true
+	}
+	return false
+}
+
+func companyUserResponse(user *models.CompanyUser) gin.H {
+	return gin.H{
+		"id":          user.ID,
+		"email":       user.Email,
+		"name":        user.Name,
+		"role":        user.Role,
+		"language":    user.Language,
+		"accepted":    user.AcceptedAt != nil,
+		"accepted_at": user.AcceptedAt,
+		"created_at":  user.CreatedAt,
+	}
+}
+
+func companyClaims(company *models.Company) auth.Claims {
+	return auth.Claims{
+		Role:             auth.RoleCompany,
+		CompanyRole:      models.CompanyRoleOwner,
+		Username:         company.Email,
+		ResetRequired:    company.ResetRequired,
+		TokenVersion:     company.TokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: company.ID.String()},
+	}
+}
+
+func companyUserClaims(user *models.CompanyUser) auth.Claims {
+	return auth.Claims{
+		Role:             auth.RoleCompany,
+		CompanyRole:      user.Role,
+		UserID:           user.ID.String(),
+		Username:         user.Email,
+		TokenVersion:     user.TokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: user.CompanyID.String()},
+	}
+}
+
+// normalizeStaffEmail is the form in which staff emails are stored and looked up
+func normalizeStaffEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// isStaffEmail reports whether the email is taken by a staff account of any company
+func (h *CompanyHandler) isStaffEmail(c *gin.Context, email string) bool {
+	_, err := h.repo.GetCompanyUserByEmail(c.Request.Context(), normalizeStaffEmail(email))
+	return err == nil
+}
+
+// companyUserLogin authenticates a staff account, ok is false when the email does not belong to one
+func (h *CompanyHandler) companyUserLogin(c *gin.Context, email, password string) bool {
+	user, err := h.repo.GetCompanyUserByEmail(c.Request.Context(), normalizeStaffEmail(email))
+	if err != nil {
+		return false
+	}
+
+	// A pending invite gets the same answer as a wrong password, so the public
+	// login endpoint does not reveal which emails have been invited
+	if user.AcceptedAt == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return true
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return true
+	}
+
+	pair, err := h.tokens.IssuePair(c.Request.Context(), companyUserClaims(user))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return true
+	}
+
+	response := pair.Fields()
+	response["role"] = user.Role
+	response["reset_required"] = false
+	c.JSON(http.StatusOK, response)
+	return true
+}
+
+func (h *CompanyHandler) GetUsers(c *gin.Context) {
+	companyID := pkg.CompanyID(c)
+
+	users, err := h.repo.GetCompanyUsers(c.Request.Context(), companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := make([]gin.H, 0, len(users))
+	for i := range users {
+		response = append(response, companyUserResponse(&users[i]))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *CompanyHandler) InviteUser(c *gin.Context) {
+	companyID := pkg.CompanyID(c)
+
+	var input struct {
+		Email string `json:"email" binding:"required,email"`
+		Name  string `json:"name"`
+		Role  string `json:"role" binding:"required"`
+		// Email language, the company's language by default
+		Language string `json:"language"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !isValidCompanyRole(input.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be owner, operator or viewer"})
+		return
+	}
+
+	company, err := h.repo.GetCompanyByID(c.Request.Context(), companyID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		return
+	}
+
+	// Login looks up company accounts first, so a staff account with the same email could never sign in
+	email := normalizeStaffEmail(input.Email)
+	if _, err := h.repo.GetCompanyByEmail(c.Request.Context(), email); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already belongs to a company account"})
+		return
+	}
+
+	code, err := generateTempPassword(24)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate invite"})
+		return
+	}
+	expiresAt := time.Now().Add(companyInviteTTL)
+	codeHash := hashToken(code)
+
+	user := &models.CompanyUser{
+		CompanyID:       companyID,
+		Email:           email,
+		Name:            input.Name,
+		Role:            input.Role,
+		Language:        company.Language,
+		InviteCodeHash:  &codeHash,
+		InviteExpiresAt: &expiresAt,
+	}
+	if input.Language != "" {
+		user.Language = mail.NormalizeLanguage(input.Language)
+	}
+
+	if err := h.repo.CreateCompanyUserWithEmail(c.Request.Context(), user, mail.CompanyInvite(user.Email, user.Language, company.Name, code, int(companyInviteTTL.Hours()/24))); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "User already exists"})
+		return
+	}
+	h.audit.Record(c, "company_user.invite", "company_user", user.ID, nil, companyUserResponse(user))
+
+	c.JSON(http.StatusCreated, companyUserResponse(user))
+}
+
+func (h *CompanyHandler) UpdateUserRole(c *gin.Context) {
+	user, ok := h.findCompanyUser(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Role string `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !isValidCompanyRole(input.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be owner, operator or viewer"})
+		return
+	}
+
+	before := companyUserResponse(user)
+	user.Role = input.Role
+	// Tokens carry the role, so the old ones must stop working
+	user.TokenVersion++
+	if err := h.repo.UpdateCompanyUser(c.Request.Context(), user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.audit.Record(c, "company_user.update_role", "company_user", user.ID, before, companyUserResponse(user))
+
+	c.JSON(http.StatusOK, companyUserResponse(user))
+}
+
+func (h *CompanyHandler) DeleteUser(c *gin.Context) {
+	user, ok := h.findCompanyUser(c)
+	if !ok {
+		return
+	}
+
+	if user.ID == pkg.CompanyUserID(c) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot remove yourself"})
+		return
+	}
+
+	if err := h.repo.DeleteCompanyUser(c.Request.Context(), user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.audit.Record(c, "company_user.delete", "company_user", user.ID, companyUserResponse(user), nil)
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// changeUserPassword changes the password of the staff account behind the current token
+func (h *CompanyHandler) changeUserPassword(c *gin.Context, userID uuid.UUID, oldPassword, newPassword string) {
+	user, err := h.repo.GetCompanyUserByID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(oldPassword)); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid old password"})
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process new password"})
+		return
+	}
+
+	user.PasswordHash = string(hash)
+	user.TokenVersion++
+	if err := h.repo.UpdateCompanyUser(c.Request.Context(), user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		return
+	}
+	h.audit.Record(c, "company_user.change_password", "company_user", user.ID, nil, nil)
+
+	pair, err := h.tokens.IssuePair(c.Request.Context(), companyUserClaims(user))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	response := pair.Fields()
+	response["status"] = "password_changed"
+	c.JSON(http.StatusOK, response)
+}
+
+// AcceptInvite lets an invited staff member set a password and activate the account
+func (h *CompanyHandler) AcceptInvite(c *gin.Context) {
+	var input struct {
+		Code     string `json:"code" binding:"required"`
+		Name     string `json:"name"`
+		Password string `json:"password" binding:"required,min=8"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.repo.GetCompanyUserByInviteCode(c.Request.Context(), hashToken(input.Code))
+	if err != nil || user.InviteExpiresAt == nil || time.Now().After(*user.InviteExpiresAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired invite code"})
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password"})
+		return
+	}
+
+	now := time.Now()
+	user.PasswordHash = string(hash)
+	user.InviteCodeHash = nil
+	user.InviteExpiresAt = nil
+	user.AcceptedAt = &now
+	if input.Name != "" {
+		user.Name = input.Name
+	}
+
+	if err := h.repo.UpdateCompanyUser(c.Request.Context(), user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "invite_accepted", "user": companyUserResponse(user)})
+}
+
+// findCompanyUser loads a staff account from the :userID path parameter, limited to the caller's company
+func (h *CompanyHandler) findCompanyUser(c *gin.Context) (*models.CompanyUser, bool) {
+	companyID := pkg.CompanyID(c)
+
+	userID, err := uuid.Parse(c.Param("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil, false
+	}
+
+	user, err := h.repo.GetCompanyUserByID(c.Request.Context(), userID)
+	if err != nil || user.CompanyID != companyID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	return user, true
+}
@@ -0,0 +1,44 @@
+package company
+
+import (
+	"Cyber-chase/internal/models"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestStaffLogin(t *testing.T) {
+	store := newFakeStore()
+	router := newTestRouter(store)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("staff-password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	accepted := time.Now()
+	inviteCode := hashToken("invite-code")
+	store.users = []models.CompanyUser{
+		{ID: uuid.New(), CompanyID: uuid.New(), Email: "alice@example.com", PasswordHash: string(hash), Role: models.CompanyRoleOperator, AcceptedAt: &accepted},
+		{ID: uuid.New(), CompanyID: uuid.New(), Email: "bob@example.com", Role: models.CompanyRoleViewer, InviteCodeHash: &inviteCode},
+	}
+	for _, user := range store.users {
+		store.companies[user.CompanyID] = models.Company{ID: user.CompanyID}
+	}
+
+	status, body := login(t, router, "  Alice@Example.COM ", "staff-password")
+	if status != http.StatusOK || body["role"] != models.CompanyRoleOperator {
+		t.Fatalf("login with a differently cased email = %d %v, want 200 as operator", status, body)
+	}
+
+	_, unknown := login(t, router, "nobody@example.com", "staff-password")
+	status, pending := login(t, router, "bob@example.com", "staff-password")
+	if status != http.StatusUnauthorized {
+		t.Fatalf("login with a pending invite = %d, want 401", status)
+	}
+	if pending["error"] != unknown["error"] {
+		t.Fatalf("pending invite answers %v, unknown email %v: the responses must not differ", pending, unknown)
+	}
+}
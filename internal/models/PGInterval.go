@@ -12,6 +12,9 @@ type PGInterval time.Duration
 func (d *PGInterval) Scan(value interface{}) error {
 	var str string
 	switch v := value.(type) {
+	case nil:
+		*d = PGInterval(0)
+		return nil
 	case string:
 		str = v
 	case []byte:
@@ -72,9 +75,15 @@ func (d PGInterval) Duration() time.Duration {
 // Строковое представление для отображения
 func (d PGInterval) String() string {
 	duration := time.Duration(d)
+	// Корректировки времени могут быть отрицательными
+	sign := ""
+	if duration < 0 {
+		sign = "-"
+		duration = -duration
+	}
 	hours := int(duration.Hours())
 	minutes := int(duration.Minutes()) % 60
 	seconds := int(duration.Seconds()) % 60
 
-	return fmt.Sprintf("%02d:%02d:%02d", hours, minutes, seconds)
+	return fmt.Sprintf("%s%02d:%02d:%02d", sign, hours, minutes, seconds)
 }
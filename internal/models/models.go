@@ -5,12 +5,20 @@ import (
 	"time"
 )
 
+// Режимы порядка выдачи задач в контесте
+const (
+	TaskOrderFixed       = "fixed"       // по полю Order задачи
+	TaskOrderShuffled    = "shuffled"    // перемешивание по сохраненному seed команды
+	TaskOrderProgressive = "progressive" // от простых к сложным
+)
+
 type Contest struct {
 	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
 	Name      string    `gorm:"not null"`
 	StartDate time.Time
 	EndDate   time.Time
 	Status    string `gorm:"default:'pending'"`
+	TaskOrder string `gorm:"default:'fixed'"`
 	Tasks     []Task
 	CreatedAt time.Time
 }
@@ -19,38 +27,175 @@ type Company struct {
 	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
 	Name          string    `gorm:"unique;not null"`
 	Email         string    `gorm:"unique;not null"`
-	TempPassword  string    `gorm:"-"`
 	PasswordHash  string    `gorm:"not null"`
 	ResetRequired bool      `gorm:"default:true"`
-	Location      string
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
+	// Срок действия временного пароля, после смены пароля nil
+	PasswordExpiresAt *time.Time
+	// Одноразовая ссылка для первой установки пароля, в базе только хеш
+	SetupTokenHash      string `gorm:"index"`
+	SetupTokenExpiresAt *time.Time
+	Location            string
+	// Язык писем компании
+	Language string `gorm:"default:'ru'"`
+	// Сколько команд компания готова принимать одновременно, 0 - без ограничений
+	Capacity int `gorm:"default:0"`
+	// Увеличивается при смене пароля, чтобы отозвать выданные токены
+	TokenVersion int `gorm:"default:0"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// Admin учетная запись администратора платформы
+type Admin struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	Username     string    `gorm:"unique;not null"`
+	PasswordHash string    `gorm:"not null"`
+	TokenVersion int       `gorm:"default:0"`
+	LastLoginAt  *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// AdminLoginAttempt запись журнала входов администраторов, включая неудачные
+type AdminLoginAttempt struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	AdminID   *uuid.UUID `gorm:"type:uuid;index"`
+	Username  string     `gorm:"not null"`
+	IP        string
+	UserAgent string
+	Success   bool      `gorm:"default:false"`
+	CreatedAt time.Time `gorm:"index"`
 }
 
+// Роли сотрудников компании
+const (
+	CompanyRoleOwner    = "owner"
+	CompanyRoleOperator = "operator"
+	CompanyRoleViewer   = "viewer"
+)
+
+// CompanyUser сотрудник или волонтер компании со своей учетной записью.
+// Пока приглашение не принято, пароль не задан и войти нельзя.
+type CompanyUser struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	CompanyID       uuid.UUID `gorm:"type:uuid;not null;index"`
+	Email           string    `gorm:"unique;not null"`
+	Name            string
+	PasswordHash    string
+	Role            string  `gorm:"not null;default:'viewer'"`
+	Language        string  `gorm:"default:'ru'"`
+	InviteCodeHash  *string `gorm:"unique"` // SHA-256 кода приглашения, сам код только в письме
+	InviteExpiresAt *time.Time
+	AcceptedAt      *time.Time
+	TokenVersion    int `gorm:"default:0"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// Типы ответов на задачу
+const (
+	AnswerTypeText  = "text"
+	AnswerTypePhoto = "photo"
+	AnswerTypeQR    = "qr"
+)
+
 type Task struct {
 	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
 	Question      string    `gorm:"not null"`
 	QuestionFile  string
+	AnswerType    string `gorm:"default:'text'"`
 	CorrectAnswer string `gorm:"not null"`
 	TimeLimit     int
+	Order         int `gorm:"column:sort_order;default:0"`
+	Difficulty    int `gorm:"default:1"`
 	ContestID     uuid.UUID
 	CompanyID     uuid.UUID
+	Hints         []TaskHint `gorm:"foreignKey:TaskID"`
 	CreatedAt     time.Time
 }
 
+// TaskHint подсказка к задаче, открывается командой по порядку Position
+type TaskHint struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	TaskID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	Position  int       `gorm:"not null"`
+	Text      string    `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+// Статусы команды
+const (
+	TeamStatusActive       = "active"
+	TeamStatusDisqualified = "disqualified"
+)
+
 type Team struct {
 	ID            uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
 	Name          string     `gorm:"not null"`
 	Email         string     `gorm:"unique;not null"`
 	PasswordHash  string     `gorm:"not null"`
-	TelegramID    int64      `gorm:"unique"`
+	ResetRequired bool       `gorm:"default:false"`
+	TelegramID    *int64     `gorm:"unique"` // чат капитана, nil если не привязан
 	ContestID     *uuid.UUID `gorm:"type:uuid"`
 	CurrentTaskID *uuid.UUID `gorm:"type:uuid"`
 	CompanyID     *uuid.UUID `gorm:"type:uuid"`
+	Status        string     `gorm:"default:'active';index"`
+	// Причина дисквалификации, видна команде
+	DisqualifyReason string
+	// Язык писем команды
+	Language      string `gorm:"default:'ru'"`
+	TaskSeed      int64
 	Points        int        `gorm:"default:0"`
 	TotalDuration PGInterval `gorm:"type:interval"`
+	// Одноразовый код сброса пароля, отправленный на почту
+	ResetCodeHash      string
+	ResetCodeExpiresAt *time.Time
+	ResetCodeAttempts  int `gorm:"default:0"`
+	TokenVersion       int `gorm:"default:0"`
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+// Роли участников команды
+const (
+	MemberRoleCaptain = "captain"
+	MemberRoleMember  = "member"
+)
+
+// TeamMember Telegram-аккаунт участника команды
+type TeamMember struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	TeamID      uuid.UUID `gorm:"type:uuid;not null;index"`
+	TelegramID  int64     `gorm:"unique;not null"`
+	DisplayName string
+	Role        string `gorm:"default:'member'"`
+	CreatedAt   time.Time
+}
+
+// TeamInvite одноразовый код приглашения в команду, выданный капитаном
+type TeamInvite struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	TeamID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	Code      string    `gorm:"unique;not null"`
+	CreatedBy int64
+	ExpiresAt time.Time
+	UsedBy    int64
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// TeamAdjustment ручная корректировка очков и времени команды судьями.
+// Отозванные записи остаются в журнале, но не учитываются в рейтинге.
+type TeamAdjustment struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	TeamID        uuid.UUID  `gorm:"type:uuid;not null;index"`
+	DeltaPoints   int        `gorm:"default:0"`
+	DeltaDuration PGInterval `gorm:"type:interval;default:'0 seconds'"`
+	Reason        string     `gorm:"not null"`
+	Author        string
+	RevokedAt     *time.Time
+	RevokedBy     string
 	CreatedAt     time.Time
-	UpdatedAt     time.Time
 }
 
 // TeamAnswer представляет ответ команды на задачу
@@ -77,7 +222,166 @@ type TeamTaskSession struct {
 	TaskID    uuid.UUID `gorm:"not null"`
 	StartTime time.Time
 	Attempts  int  `gorm:"default:0"`
+	HintsUsed int  `gorm:"default:0"`
 	Finished  bool `gorm:"default:false"`
 	IsCorrect bool `gorm:"default:false"`
+	Skipped   bool `gorm:"default:false"`
+	// Итоги заполняются при завершении сессии
+	FinishedAt *time.Time
+	Duration   PGInterval `gorm:"type:interval;default:'0 seconds'"`
+	Points     int        `gorm:"default:0"`
+	CreatedAt  time.Time
+}
+
+// Статусы проверки присланных командой файлов
+const (
+	SubmissionPending  = "pending"
+	SubmissionAccepted = "accepted"
+	SubmissionRejected = "rejected"
+)
+
+// TeamSubmission фото или документ, присланный командой в качестве ответа
+// и ожидающий ручной проверки компанией
+type TeamSubmission struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	TeamID     uuid.UUID `gorm:"type:uuid;not null"`
+	TaskID     uuid.UUID `gorm:"type:uuid;not null"`
+	CompanyID  uuid.UUID `gorm:"type:uuid;not null;index"`
+	SessionID  uuid.UUID `gorm:"type:uuid;not null"`
+	FileName   string    `gorm:"not null"`
+	Status     string    `gorm:"default:'pending';index"`
+	Comment    string
+	ReviewedAt *time.Time
+	CreatedAt  time.Time
+}
+
+// Статусы заявок команд на прохождение точки компании
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+	// Команда передумала и выбрала другую компанию
+	ApprovalCancelled = "cancelled"
+	// Команда прошла все задачи компании и освободила место
+	ApprovalReleased = "released"
+)
+
+// ApprovalRequest заявка команды на прохождение задач конкретной компании
+type ApprovalRequest struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	TeamID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	CompanyID uuid.UUID `gorm:"type:uuid;not null;index"`
+	ContestID uuid.UUID `gorm:"type:uuid;not null"`
+	Status    string    `gorm:"default:'pending';index"`
+	Reason    string
+	DecidedAt *time.Time
+	CreatedAt time.Time
+}
+
+// RefreshToken долгоживущий токен обновления. В базе хранится только хеш,
+// вместе со снимком claims, из которых выпускается новый access-токен.
+type RefreshToken struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	TokenHash     string    `gorm:"unique;not null"`
+	Role          string    `gorm:"not null"`
+	SubjectID     string    `gorm:"not null;index"`
+	UserID        string
+	CompanyRole   string
+	Username      string
+	ResetRequired bool
+	TokenVersion  int
+	ExpiresAt     time.Time
+	RevokedAt     *time.Time
+	CreatedAt     time.Time
+}
+
+// Типы подозрительных ответов
+const (
+	// Правильный ответ через считанные секунды после выдачи задачи
+	FlagFastCorrect = "fast_correct"
+	// Такой же неправильный ответ уже присылала другая команда
+	FlagSharedWrongAnswer = "shared_wrong_answer"
+	// Ответ на задачу компании, к которой команда сейчас не допущена: заявка не одобрена,
+	// команда уже освобождена или переведена в другую компанию
+	FlagNoCheckIn = "no_check_in"
+)
+
+// AnswerFlag отметка о подозрительном ответе для проверки судьями.
+// На одну сессию приходится не больше одной отметки каждого типа.
+type AnswerFlag struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SessionID uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_answer_flags_session_kind,priority:1"`
+	TeamID    uuid.UUID  `gorm:"type:uuid;not null;index"`
+	TaskID    uuid.UUID  `gorm:"type:uuid;not null"`
+	ContestID *uuid.UUID `gorm:"type:uuid;index"`
+	Kind      string     `gorm:"not null;index;uniqueIndex:idx_answer_flags_session_kind,priority:2"`
+	Details   string
 	CreatedAt time.Time
 }
+
+// AuditLog запись журнала действий администраторов и компаний. Записи только
+// добавляются, состояние сущности до и после действия хранится в JSON.
+type AuditLog struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	ActorID     string     `gorm:"index" json:"actor_id"`
+	ActorName   string     `json:"actor_name"`
+	Role        string     `gorm:"index" json:"role"`
+	CompanyRole string     `json:"company_role,omitempty"`
+	CompanyID   *uuid.UUID `gorm:"type:uuid;index" json:"company_id,omitempty"`
+	Action      string     `gorm:"not null;index" json:"action"`
+	EntityType  string     `gorm:"index" json:"entity_type"`
+	EntityID    string     `gorm:"index" json:"entity_id"`
+	Before      string     `gorm:"type:text" json:"before,omitempty"`
+	After       string     `gorm:"type:text" json:"after,omitempty"`
+	IP          string     `json:"ip"`
+	CreatedAt   time.Time  `gorm:"index" json:"created_at"`
+}
+
+// Статусы писем в очереди отправки
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	// Все попытки исчерпаны, письмо ждет ручной переотправки
+	OutboxDead = "dead"
+)
+
+// OutboxEmail письмо в очереди отправки. Создается в той же транзакции, что и
+// сущность, о которой письмо, и отправляется фоновым обработчиком. Письмо
+// собирается из шаблона Kind на языке Language в момент отправки.
+type OutboxEmail struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	Kind      string    `gorm:"not null;uniqueIndex:idx_outbox_contest_mail,priority:1" json:"kind"`
+	Recipient string    `gorm:"not null;uniqueIndex:idx_outbox_contest_mail,priority:2" json:"recipient"`
+	Language  string    `json:"language"`
+	// Контест рассылки. Одно письмо каждого типа на адрес в рамках контеста,
+	// повторный запуск рассылки дописывает только новых получателей
+	ContestID *uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_outbox_contest_mail,priority:3" json:"contest_id,omitempty"`
+	// Заполняется при сборке письма
+	Subject string `json:"subject"`
+	// Данные шаблона в JSON. Могут содержать пароли и коды, поэтому
+	// не отдаются наружу и стираются после успешной отправки
+	Data          string     `gorm:"type:text" json:"-"`
+	Status        string     `gorm:"default:'pending';index" json:"status"`
+	Attempts      int        `gorm:"default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"index" json:"next_attempt_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// ServiceKey ключ для вызовов внутреннего API другими сервисами. Хранится
+// только хеш, сам ключ показывается один раз при создании.
+type ServiceKey struct {
+	ID      uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	Name    string    `gorm:"unique;not null" json:"name"`
+	KeyHash string    `gorm:"unique;not null" json:"-"`
+	// Начало ключа, чтобы его можно было узнать в списке
+	Prefix string `json:"prefix"`
+	// Разрешения через запятую, например "leaderboard:read,teams:read"
+	Scopes     string     `json:"scopes"`
+	CreatedBy  string     `json:"created_by"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
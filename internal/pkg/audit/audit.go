@@ -0,0 +1,146 @@
+package audit
+
+import (
+	"Cyber-chase/internal/models"
+	"Cyber-chase/internal/pkg"
+	"Cyber-chase/internal/pkg/auth"
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Store хранилище журнала. Журнал только пополняется, изменять и удалять записи нельзя.
+type Store interface {
+	CreateAuditLog(ctx context.Context, entry *models.AuditLog) error
+	FindAuditLogs(ctx context.Context, filter Filter) ([]models.AuditLog, error)
+}
+
+// Filter условия отбора записей журнала, пустые поля не учитываются
+type Filter struct {
+	ActorID    string
+	Role       string
+	CompanyID  *uuid.UUID
+	Action     string
+	EntityType string
+	EntityID   string
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+}
+
+// Recorder пишет в журнал действия администраторов и компаний
+type Recorder struct {
+	store Store
+}
+
+func NewRecorder(store Store) *Recorder {
+	return &Recorder{store: store}
+}
+
+// Actor автор действия, выполненного вне HTTP-запроса администратора или компании:
+// команда в боте или сам сервер
+type Actor struct {
+	ID   string
+	Name string
+	Role string
+}
+
+// SystemActor автор действий, которые сервер выполняет сам
+var SystemActor = Actor{Role: "system", Name: "system"}
+
+// Record записывает действие автора текущего запроса. before и after сохраняются
+// как JSON без паролей, хешей и кодов. Ошибка записи не должна ломать само
+// действие, поэтому она только логируется.
+func (r *Recorder) Record(c *gin.Context, action, entityType string, entityID uuid.UUID, before, after interface{}) {
+	entry := newEntry(action, entityType, entityID, before, after)
+	entry.IP = c.ClientIP()
+
+	if claims := pkg.Claims(c); claims != nil {
+		entry.Role = claims.Role
+		entry.ActorID = claims.Subject
+		entry.ActorName = claims.Username
+		if claims.Role == auth.RoleCompany {
+			companyID := pkg.CompanyID(c)
+			entry.CompanyID = &companyID
+			entry.CompanyRole = claims.CompanyRole
+			if claims.UserID != "" {
+				entry.ActorID = claims.UserID
+			}
+		}
+	}
+
+	r.save(c.Request.Context(), entry)
+}
+
+// RecordAs записывает действие от имени actor, например действие команды в боте
+func (r *Recorder) RecordAs(ctx context.Context, actor Actor, action, entityType string, entityID uuid.UUID, before, after interface{}) {
+	entry := newEntry(action, entityType, entityID, before, after)
+	entry.ActorID = actor.ID
+	entry.ActorName = actor.Name
+	entry.Role = actor.Role
+
+	r.save(ctx, entry)
+}
+
+func newEntry(action, entityType string, entityID uuid.UUID, before, after interface{}) *models.AuditLog {
+	entry := &models.AuditLog{
+		Action:     action,
+		EntityType: entityType,
+		Before:     snapshot(before),
+		After:      snapshot(after),
+	}
+	if entityID != uuid.Nil {
+		entry.EntityID = entityID.String()
+	}
+	return entry
+}
+
+func (r *Recorder) save(ctx context.Context, entry *models.AuditLog) {
+	if err := r.store.CreateAuditLog(ctx, entry); err != nil {
+		log.Printf("audit: failed to record %s on %s %s: %v", entry.Action, entry.EntityType, entry.EntityID, err)
+	}
+}
+
+// snapshot сериализует состояние сущности, убирая секреты
+func snapshot(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		// Не объект, сохраняем как есть
+		return string(data)
+	}
+	for key := range fields {
+		if isSecret(key) {
+			delete(fields, key)
+		}
+	}
+
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func isSecret(key string) bool {
+	key = strings.ToLower(key)
+	for _, marker := range []string{"password", "hash", "code", "token"} {
+		if strings.Contains(key, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package audit
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultLimit число записей в ответе GetLogs без параметра limit
+	DefaultLimit = 100
+	// MaxLimit наибольший limit для просмотра и выгрузки, выгрузка без limit ограничена им же
+	MaxLimit = 10000
+)
+
+// Handler просмотр и выгрузка журнала для администраторов
+type Handler struct {
+	store Store
+}
+
+func NewHandler(store Store) *Handler {
+	return &Handler{store: store}
+}
+
+// GetLogs возвращает записи журнала. Фильтры: actor_id, role, company_id, action,
+// entity_type, entity_id, from и to в RFC3339, limit (по умолчанию DefaultLimit).
+func (h *Handler) GetLogs(c *gin.Context) {
+	filter, err := parseFilter(c, DefaultLimit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries, err := h.store.FindAuditLogs(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// ExportCSV выгружает записи журнала в CSV с теми же фильтрами, по умолчанию MaxLimit записей
+func (h *Handler) ExportCSV(c *gin.Context) {
+	filter, err := parseFilter(c, MaxLimit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries, err := h.store.FindAuditLogs(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	fileName := fmt.Sprintf("audit-%s.csv", time.Now().Format("20060102-150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	_ = writer.Write([]string{"id", "created_at", "actor_id", "actor_name", "role", "company_role", "company_id", "action", "entity_type", "entity_id", "ip", "before", "after"})
+	for _, entry := range entries {
+		companyID := ""
+		if entry.CompanyID != nil {
+			companyID = entry.CompanyID.String()
+		}
+		_ = writer.Write(csvRow(
+			entry.ID.String(),
+			entry.CreatedAt.Format(time.RFC3339),
+			entry.ActorID,
+			entry.ActorName,
+			entry.Role,
+			entry.CompanyRole,
+			companyID,
+			entry.Action,
+			entry.EntityType,
+			entry.EntityID,
+			entry.IP,
+			entry.Before,
+			entry.After,
+		))
+	}
+	writer.Flush()
+}
+
+// csvRow экранирует ячейки, которые табличный редактор принял бы за формулу:
+// имя сотрудника или значение в журнале может начинаться с "=", "+", "-", "@", табуляции или возврата каретки
+func csvRow(cells ...string) []string {
+	for i, cell := range cells {
+		if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+			cells[i] = "'" + cell
+		}
+	}
+	return cells
+}
+
+func parseFilter(c *gin.Context, defaultLimit int) (Filter, error) {
+	filter := Filter{
+		ActorID:    c.Query("actor_id"),
+		Role:       c.Query("role"),
+		Action:     c.Query("action"),
+		EntityType: c.Query("entity_type"),
+		EntityID:   c.Query("entity_id"),
+		Limit:      defaultLimit,
+	}
+
+	if value := c.Query("company_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return filter, errors.New("Invalid company ID")
+		}
+		filter.CompanyID = &id
+	}
+	if value := c.Query("from"); value != "" {
+		from, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, errors.New("Invalid from, expected RFC3339")
+		}
+		filter.From = &from
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, errors.New("Invalid to, expected RFC3339")
+		}
+		filter.To = &to
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > MaxLimit {
+			return filter, fmt.Errorf("Limit must be between 1 and %d", MaxLimit)
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
@@ -0,0 +1,248 @@
+package auth
+
+import (
+	"Cyber-chase/internal/models"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+// Роли владельцев токенов
+const (
+	RoleAdmin   = "admin"
+	RoleCompany = "company"
+	RoleTeam    = "team"
+)
+
+const (
+	DefaultAccessTTL  = 15 * time.Minute
+	DefaultRefreshTTL = 30 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrEmptySecret JWT_SECRET не задан, токены можно было бы подделать
+	ErrEmptySecret = errors.New("JWT_SECRET is not set")
+	// ErrTokenRevoked токен выпущен до смены пароля или отозван
+	ErrTokenRevoked = errors.New("token revoked")
+)
+
+// Claims содержимое access-токена любой роли
+type Claims struct {
+	Role string `json:"role"`
+	// Роль сотрудника и его ID для токенов компании
+	CompanyRole string `json:"company_role,omitempty"`
+	UserID      string `json:"user_id,omitempty"`
+	Username    string `json:"username,omitempty"`
+	// Требуется смена временного пароля
+	ResetRequired bool `json:"reset_required,omitempty"`
+	TokenVersion  int  `json:"token_version"`
+	jwt.RegisteredClaims
+}
+
+// SubjectID возвращает ID владельца токена: администратора, компании или команды
+func (c *Claims) SubjectID() (uuid.UUID, error) {
+	return uuid.Parse(c.Subject)
+}
+
+// TokenPair access- и refresh-токены, которые выдаются при входе
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	// Время жизни access-токена в секундах
+	ExpiresIn int `json:"expires_in"`
+}
+
+// Store хранит refresh-токены и текущие версии токенов владельцев
+type Store interface {
+	TokenVersion(ctx context.Context, role, subjectID, userID string) (int, error)
+	SaveRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	FindRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	// RevokeRefreshToken отзывает токен, если он еще не отозван. Возвращает false,
+	// если токен уже отозван, например параллельным обновлением.
+	RevokeRefreshToken(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+// Manager выпускает и проверяет токены. Принимается только HS256.
+type Manager struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	store      Store
+}
+
+func NewManager(secret string, accessTTL, refreshTTL time.Duration, store Store) *Manager {
+	return &Manager{
+		secret:     []byte(secret),
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+		store:      store,
+	}
+}
+
+// NewManagerFromEnv берет секрет из JWT_SECRET, а время жизни токенов
+// из ACCESS_TOKEN_TTL и REFRESH_TOKEN_TTL (формат time.ParseDuration).
+// Пустой секрет считается ошибкой конфигурации.
+func NewManagerFromEnv(store Store) (*Manager, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return nil, ErrEmptySecret
+	}
+	return NewManager(
+		secret,
+		envDuration("ACCESS_TOKEN_TTL", DefaultAccessTTL),
+		envDuration("REFRESH_TOKEN_TTL", DefaultRefreshTTL),
+		store,
+	), nil
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// IssueAccessToken подписывает access-токен с заданными claims
+func (m *Manager) IssueAccessToken(claims Claims) (string, error) {
+	now := time.Now()
+	claims.ID = uuid.NewString()
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(m.accessTTL))
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+}
+
+// IssuePair выпускает access-токен и сохраняет новый refresh-токен
+func (m *Manager) IssuePair(ctx context.Context, claims Claims) (*TokenPair, error) {
+	access, err := m.IssueAccessToken(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.store.SaveRefreshToken(ctx, &models.RefreshToken{
+		TokenHash:     hashToken(refresh),
+		Role:          claims.Role,
+		SubjectID:     claims.Subject,
+		UserID:        claims.UserID,
+		CompanyRole:   claims.CompanyRole,
+		Username:      claims.Username,
+		ResetRequired: claims.ResetRequired,
+		TokenVersion:  claims.TokenVersion,
+		ExpiresAt:     time.Now().Add(m.refreshTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresIn:    int(m.accessTTL.Seconds()),
+	}, nil
+}
+
+// Verify проверяет подпись, срок действия и версию access-токена
+func (m *Manager) Verify(ctx context.Context, tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	token, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	})
+	if err != nil || !token.Valid || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+
+	if err := m.checkVersion(ctx, claims.Role, claims.Subject, claims.UserID, claims.TokenVersion); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Refresh обменивает refresh-токен на новую пару. Старый refresh-токен отзывается.
+func (m *Manager) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	stored, err := m.store.FindRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if stored.RevokedAt != nil {
+		return nil, ErrTokenRevoked
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	if err := m.checkVersion(ctx, stored.Role, stored.SubjectID, stored.UserID, stored.TokenVersion); err != nil {
+		return nil, err
+	}
+
+	// Отзыв условный: из двух одновременных обновлений одним токеном пройдет только одно
+	revoked, err := m.store.RevokeRefreshToken(ctx, stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	return m.IssuePair(ctx, Claims{
+		Role:             stored.Role,
+		CompanyRole:      stored.CompanyRole,
+		UserID:           stored.UserID,
+		Username:         stored.Username,
+		ResetRequired:    stored.ResetRequired,
+		TokenVersion:     stored.TokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: stored.SubjectID},
+	})
+}
+
+// Revoke отзывает refresh-токен при выходе
+func (m *Manager) Revoke(ctx context.Context, refreshToken string) error {
+	stored, err := m.store.FindRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		return ErrInvalidToken
+	}
+	if stored.RevokedAt != nil {
+		return nil
+	}
+	_, err = m.store.RevokeRefreshToken(ctx, stored.ID)
+	return err
+}
+
+func (m *Manager) checkVersion(ctx context.Context, role, subjectID, userID string, version int) error {
+	current, err := m.store.TokenVersion(ctx, role, subjectID, userID)
+	if err != nil {
+		// Владелец токена удален
+		return ErrTokenRevoked
+	}
+	if current != version {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
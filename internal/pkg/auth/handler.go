@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler общие эндпоинты обновления токена и выхода для всех ролей
+type Handler struct {
+	tokens *Manager
+}
+
+func NewHandler(tokens *Manager) *Handler {
+	return &Handler{tokens: tokens}
+}
+
+// Fields возвращает токены в виде ответа, к которому обработчики добавляют свои поля
+func (p *TokenPair) Fields() gin.H {
+	return gin.H{
+		"token":         p.AccessToken,
+		"refresh_token": p.RefreshToken,
+		"expires_in":    p.ExpiresIn,
+	}
+}
+
+func (h *Handler) Refresh(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pair, err := h.tokens.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrTokenRevoked) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, pair)
+}
+
+func (h *Handler) Logout(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.tokens.Revoke(c.Request.Context(), req.RefreshToken); err != nil && !errors.Is(err, ErrInvalidToken) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "logged_out"})
+}
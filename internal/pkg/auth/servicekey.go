@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"Cyber-chase/internal/models"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Разрешения ключей внутреннего API
+const (
+	ScopeLeaderboardRead = "leaderboard:read"
+	ScopeTeamsRead       = "teams:read"
+)
+
+// Scopes все известные разрешения
+var Scopes = []string{ScopeLeaderboardRead, ScopeTeamsRead}
+
+// serviceKeyPrefix отличает ключи сервисов от JWT и паролей
+const serviceKeyPrefix = "ccsk_"
+
+var (
+	ErrInvalidServiceKey = errors.New("invalid service key")
+	ErrScopeDenied       = errors.New("service key scope denied")
+)
+
+// ServiceKeyStore хранилище ключей внутреннего API
+type ServiceKeyStore interface {
+	FindServiceKeyByHash(ctx context.Context, hash string) (*models.ServiceKey, error)
+	TouchServiceKey(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+}
+
+// NewServiceKey выпускает ключ с указанными разрешениями. Возвращает модель
+// для сохранения и сам ключ, который больше нигде не хранится.
+func NewServiceKey(name, createdBy string, scopes []string) (*models.ServiceKey, string, error) {
+	for _, scope := range scopes {
+		if !validScope(scope) {
+			return nil, "", errors.New("unknown scope " + scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, "", errors.New("at least one scope is required")
+	}
+
+	secret, err := randomToken()
+	if err != nil {
+		return nil, "", err
+	}
+	key := serviceKeyPrefix + secret
+
+	return &models.ServiceKey{
+		Name:      name,
+		KeyHash:   hashToken(key),
+		Prefix:    key[:len(serviceKeyPrefix)+6],
+		Scopes:    strings.Join(scopes, ","),
+		CreatedBy: createdBy,
+	}, key, nil
+}
+
+// VerifyServiceKey проверяет ключ и наличие у него разрешения scope.
+// Принимаются только ключи с префиксом сервиса, поэтому ни пароль, ни
+// хеш пароля из базы, ни токен пользователя ключом быть не могут.
+func VerifyServiceKey(ctx context.Context, store ServiceKeyStore, key, scope string) (*models.ServiceKey, error) {
+	if !strings.HasPrefix(key, serviceKeyPrefix) {
+		return nil, ErrInvalidServiceKey
+	}
+
+	// Ключ ищется по SHA-256 хешу, сравнивать найденный хеш еще раз незачем
+	stored, err := store.FindServiceKeyByHash(ctx, hashToken(key))
+	if err != nil {
+		return nil, ErrInvalidServiceKey
+	}
+	if stored.RevokedAt != nil {
+		return nil, ErrInvalidServiceKey
+	}
+	if !HasScope(stored, scope) {
+		return nil, ErrScopeDenied
+	}
+
+	_ = store.TouchServiceKey(ctx, stored.ID, time.Now())
+	return stored, nil
+}
+
+// HasScope проверяет, что ключу выдано разрешение
+func HasScope(key *models.ServiceKey, scope string) bool {
+	for _, s := range strings.Split(key.Scopes, ",") {
+		if strings.TrimSpace(s) == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func validScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
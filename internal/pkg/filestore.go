@@ -1,6 +1,7 @@
 package pkg
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -12,6 +13,18 @@ import (
 
 const UploadDir = "./uploads"
 
+// SubmissionDir is the subdirectory of UploadDir for files sent by teams
+const SubmissionDir = "submissions"
+
+// MaxSubmissionSize limits a file sent by a team (the Telegram bot API serves files up to 20 MB)
+const MaxSubmissionSize = 20 << 20
+
+// ErrFileTooLarge is returned when a submission exceeds MaxSubmissionSize
+var ErrFileTooLarge = errors.New("file is too large")
+
+// ErrInvalidFileName is returned when a submission has no usable file name
+var ErrInvalidFileName = errors.New("invalid file name")
+
 func init() {
 	if err := os.MkdirAll(UploadDir, 0755); err != nil {
 		panic(fmt.Sprintf("Failed to create upload directory: %v", err))
@@ -63,3 +76,51 @@ func DeleteTaskFiles(taskID uuid.UUID) error {
 	taskDir := filepath.Join(UploadDir, taskID.String())
 	return os.RemoveAll(taskDir)
 }
+
+// SaveSubmissionFile saves a file sent by a team as an answer and returns the path
+func SaveSubmissionFile(src io.Reader, submissionID uuid.UUID, filename string) (string, error) {
+	// filepath.Base turns an empty name into "." and a bare separator into "/"
+	name := filepath.Base(filename)
+	if name == "." || name == ".." || name == string(filepath.Separator) {
+		return "", ErrInvalidFileName
+	}
+
+	submissionDir := filepath.Join(UploadDir, SubmissionDir, submissionID.String())
+	if err := os.MkdirAll(submissionDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create submission directory: %w", err)
+	}
+
+	path := filepath.Join(submissionDir, name)
+
+	dst, err := os.Create(path)
+	if err != nil {
+		os.RemoveAll(submissionDir)
+		return "", fmt.Errorf("error creating destination file: %w", err)
+	}
+	defer dst.Close()
+
+	// Read one byte past the limit to tell an oversized file from one of exactly the maximum size
+	written, err := io.Copy(dst, io.LimitReader(src, MaxSubmissionSize+1))
+	if err != nil {
+		dst.Close()
+		os.RemoveAll(submissionDir)
+		return "", fmt.Errorf("error copying file contents: %w", err)
+	}
+	if written > MaxSubmissionSize {
+		dst.Close()
+		os.RemoveAll(submissionDir)
+		return "", ErrFileTooLarge
+	}
+
+	return path, nil
+}
+
+// GetSubmissionFilePath returns the full path to a file sent by a team
+func GetSubmissionFilePath(submissionID uuid.UUID, filename string) string {
+	return filepath.Join(UploadDir, SubmissionDir, submissionID.String(), filename)
+}
+
+// DeleteSubmissionFiles removes all files associated with a team submission
+func DeleteSubmissionFiles(submissionID uuid.UUID) error {
+	return os.RemoveAll(filepath.Join(UploadDir, SubmissionDir, submissionID.String()))
+}
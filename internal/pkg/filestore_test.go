@@ -0,0 +1,55 @@
+package pkg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/google/uuid"
+)
+
+func TestSaveSubmissionFileRejectsEmptyName(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	for _, name := range []string{"", ".", "..", "/", "dir/.."} {
+		id := uuid.New()
+		if _, err := SaveSubmissionFile(strings.NewReader("data"), id, name); !errors.Is(err, ErrInvalidFileName) {
+			t.Fatalf("name %q: err = %v, want ErrInvalidFileName", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(UploadDir, SubmissionDir, id.String())); !os.IsNotExist(err) {
+			t.Fatalf("name %q: submission directory was created", name)
+		}
+	}
+}
+
+func TestSaveSubmissionFileCleansUpOnReadError(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	id := uuid.New()
+	src := iotest.ErrReader(errors.New("connection reset"))
+	if _, err := SaveSubmissionFile(src, id, "photo.jpg"); err == nil {
+		t.Fatal("expected a copy error")
+	}
+	if _, err := os.Stat(filepath.Join(UploadDir, SubmissionDir, id.String())); !os.IsNotExist(err) {
+		t.Fatal("partial submission was left on disk")
+	}
+}
+
+func TestSaveSubmissionFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	id := uuid.New()
+	path, err := SaveSubmissionFile(strings.NewReader("data"), id, "../../photo.jpg")
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if path != GetSubmissionFilePath(id, "photo.jpg") {
+		t.Fatalf("path = %s, want the name inside the submission directory", path)
+	}
+	if content, err := os.ReadFile(path); err != nil || string(content) != "data" {
+		t.Fatalf("content = %q, %v", content, err)
+	}
+}
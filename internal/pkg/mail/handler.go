@@ -0,0 +1,82 @@
+package mail
+
+import (
+	"Cyber-chase/internal/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Handler просмотр очереди писем и ручная переотправка для администраторов
+type Handler struct {
+	store Store
+}
+
+func NewHandler(store Store) *Handler {
+	return &Handler{store: store}
+}
+
+// GetEmails возвращает письма из очереди, ?status= по умолчанию dead, "all" - все
+func (h *Handler) GetEmails(c *gin.Context) {
+	status := c.DefaultQuery("status", models.OutboxDead)
+	if status == "all" {
+		status = ""
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Limit must be between 1 and 1000"})
+		return
+	}
+
+	emails, err := h.store.GetOutboxEmails(c.Request.Context(), status, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, emails)
+}
+
+// ResendEmail возвращает недоставленное письмо в очередь с новым счетчиком попыток.
+// Письма в статусе pending и так отправляются повторно, а у писем с паролями и кодами
+// данные стерты, поэтому переотправить можно только dead письмо с сохраненными данными.
+func (h *Handler) ResendEmail(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	email, err := h.store.GetOutboxEmailByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email not found"})
+		return
+	}
+
+	if email.Status == models.OutboxSent {
+		// Данные письма стираются после отправки, переотправлять нечего
+		c.JSON(http.StatusConflict, gin.H{"error": "Email was already sent"})
+		return
+	}
+
+	requeued, err := h.store.RequeueDeadEmail(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !requeued {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only dead emails with stored data can be resent, emails with passwords or codes must be triggered again"})
+		return
+	}
+
+	email, err = h.store.GetOutboxEmailByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "queued", "email": email})
+}
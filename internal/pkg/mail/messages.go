@@ -0,0 +1,121 @@
+package mail
+
+import (
+	"Cyber-chase/internal/models"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Виды писем, каждому соответствует шаблон с тем же именем
+const (
+	KindWelcome         = "welcome"
+	KindPasswordReset   = "password_reset"
+	KindResetCode       = "reset_code"
+	KindCompanyInvite   = "company_invite"
+	KindContestReminder = "contest_reminder"
+	KindContestResults  = "contest_results"
+)
+
+// Welcome письмо после регистрации с временным паролем
+// HasSecrets сообщает, что в данных письма этого типа есть пароль, код или ссылка для входа.
+// Такие данные стираются, как только письмо отправлено или окончательно не доставлено.
+func HasSecrets(kind string) bool {
+	switch kind {
+	case KindContestReminder, KindContestResults:
+		return false
+	}
+	return true
+}
+
+func Welcome(email, lang, name, password string) *models.OutboxEmail {
+	return message(KindWelcome, email, lang, map[string]interface{}{
+		"Name":     name,
+		"Email":    email,
+		"Password": password,
+	})
+}
+
+// PasswordReset письмо с новым временным паролем, выданным администратором
+func PasswordReset(email, lang, name, password string) *models.OutboxEmail {
+	return message(KindPasswordReset, email, lang, map[string]interface{}{
+		"Name":     name,
+		"Email":    email,
+		"Password": password,
+	})
+}
+
+// CompanyWelcome письмо новой компании: временный пароль с ограниченным сроком
+// и одноразовая ссылка, по которой можно сразу задать свой пароль
+func CompanyWelcome(email, lang, name, password, setupURL string, expiresAt time.Time) *models.OutboxEmail {
+	return message(KindWelcome, email, lang, companyCredentials(email, name, password, setupURL, expiresAt))
+}
+
+// CompanyPasswordReset письмо компании с новым временным паролем и ссылкой установки пароля
+func CompanyPasswordReset(email, lang, name, password, setupURL string, expiresAt time.Time) *models.OutboxEmail {
+	return message(KindPasswordReset, email, lang, companyCredentials(email, name, password, setupURL, expiresAt))
+}
+
+func companyCredentials(email, name, password, setupURL string, expiresAt time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"Name":      name,
+		"Email":     email,
+		"Password":  password,
+		"SetupURL":  setupURL,
+		"ExpiresAt": expiresAt.Format("02.01.2006 15:04 MST"),
+	}
+}
+
+// ResetCode письмо с кодом сброса пароля команды
+func ResetCode(email, lang, code string, minutes int) *models.OutboxEmail {
+	return message(KindResetCode, email, lang, map[string]interface{}{
+		"Code":    code,
+		"Minutes": minutes,
+	})
+}
+
+// CompanyInvite приглашение сотрудника на точку компании
+func CompanyInvite(email, lang, companyName, code string, days int) *models.OutboxEmail {
+	return message(KindCompanyInvite, email, lang, map[string]interface{}{
+		"CompanyName": companyName,
+		"Code":        code,
+		"Days":        days,
+	})
+}
+
+// ContestReminder напоминание команде о скором начале соревнования
+func ContestReminder(contestID uuid.UUID, email, lang, teamName, contestName string) *models.OutboxEmail {
+	msg := message(KindContestReminder, email, lang, map[string]interface{}{
+		"Name":        teamName,
+		"ContestName": contestName,
+	})
+	msg.ContestID = &contestID
+	return msg
+}
+
+// ContestResults итоги соревнования для команды
+func ContestResults(contestID uuid.UUID, email, lang, teamName, contestName string, place, teams, points int, duration string) *models.OutboxEmail {
+	msg := message(KindContestResults, email, lang, map[string]interface{}{
+		"Name":        teamName,
+		"ContestName": contestName,
+		"Place":       place,
+		"Teams":       teams,
+		"Points":      points,
+		"Duration":    duration,
+	})
+	msg.ContestID = &contestID
+	return msg
+}
+
+func message(kind, email, lang string, data map[string]interface{}) *models.OutboxEmail {
+	// Данные состоят из строк и чисел, ошибки сериализации быть не может
+	encoded, _ := json.Marshal(data)
+	return &models.OutboxEmail{
+		Kind:      kind,
+		Recipient: email,
+		Language:  NormalizeLanguage(lang),
+		Data:      string(encoded),
+		Status:    models.OutboxPending,
+	}
+}
@@ -0,0 +1,171 @@
+package mail
+
+import (
+	"Cyber-chase/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Store очередь писем
+type Store interface {
+	ClaimDueEmails(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEmail, error)
+	UpdateOutboxEmail(ctx context.Context, email *models.OutboxEmail) error
+	GetOutboxEmailByID(ctx context.Context, id uuid.UUID) (*models.OutboxEmail, error)
+	GetOutboxEmails(ctx context.Context, status string, limit int) ([]models.OutboxEmail, error)
+	RequeueDeadEmail(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+// OutboxConfig настройки фоновой отправки
+type OutboxConfig struct {
+	// Как часто проверять очередь
+	PollInterval time.Duration
+	// Сколько писем брать за один проход
+	BatchSize int
+	// После стольких неудачных попыток письмо уходит в dead
+	MaxAttempts int
+	// Задержка перед второй попыткой, дальше удваивается до MaxBackoff
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// На сколько письмо откладывается, пока его отправляет один экземпляр
+	Lease time.Duration
+}
+
+// LoadOutboxConfig читает настройки из окружения
+func LoadOutboxConfig() OutboxConfig {
+	return OutboxConfig{
+		PollInterval: envDuration("MAIL_OUTBOX_POLL_INTERVAL", 5*time.Second),
+		BatchSize:    envInt("MAIL_OUTBOX_BATCH_SIZE", 20),
+		MaxAttempts:  envInt("MAIL_OUTBOX_MAX_ATTEMPTS", 8),
+		BaseBackoff:  envDuration("MAIL_OUTBOX_BASE_BACKOFF", 30*time.Second),
+		MaxBackoff:   envDuration("MAIL_OUTBOX_MAX_BACKOFF", time.Hour),
+		Lease:        envDuration("MAIL_OUTBOX_LEASE", 5*time.Minute),
+	}
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+func envInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// Worker фоновая отправка писем из очереди с повторами
+type Worker struct {
+	store    Store
+	sender   Sender
+	renderer *Renderer
+	config   OutboxConfig
+}
+
+func NewWorker(store Store, sender Sender, renderer *Renderer, config OutboxConfig) *Worker {
+	return &Worker{store: store, sender: sender, renderer: renderer, config: config}
+}
+
+// Run отправляет письма, пока не отменен ctx
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.flush(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// flush отправляет все письма, срок которых подошел
+func (w *Worker) flush(ctx context.Context) {
+	for ctx.Err() == nil {
+		emails, err := w.store.ClaimDueEmails(ctx, w.config.BatchSize, w.config.Lease)
+		if err != nil {
+			log.Printf("mail outbox: failed to claim emails: %v", err)
+			return
+		}
+
+		for i := range emails {
+			w.deliver(ctx, &emails[i])
+		}
+		if len(emails) < w.config.BatchSize {
+			return
+		}
+	}
+}
+
+func (w *Worker) deliver(ctx context.Context, email *models.OutboxEmail) {
+	email.Attempts++
+	err := w.send(email)
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		email.Status = models.OutboxSent
+		email.SentAt = &now
+		email.LastError = ""
+		// Пароли и коды не должны оставаться в базе дольше, чем нужно
+		email.Data = ""
+	case email.Attempts >= w.config.MaxAttempts:
+		email.Status = models.OutboxDead
+		email.LastError = err.Error()
+		// Письмо с паролем или кодом уже не будет отправлено, такое действие повторяют заново
+		if HasSecrets(email.Kind) {
+			email.Data = ""
+		}
+		log.Printf("mail outbox: giving up on %s email %s to %s after %d attempts: %v", email.Kind, email.ID, email.Recipient, email.Attempts, err)
+	default:
+		email.LastError = err.Error()
+		email.NextAttemptAt = now.Add(w.backoff(email.Attempts))
+	}
+
+	if err := w.store.UpdateOutboxEmail(ctx, email); err != nil {
+		log.Printf("mail outbox: failed to update email %s: %v", email.ID, err)
+	}
+}
+
+// send собирает письмо из шаблона и передает его отправителю. Ошибка шаблона
+// тоже считается неудачной попыткой: шаблон могут исправить в каталоге переопределения.
+func (w *Worker) send(email *models.OutboxEmail) error {
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(email.Data), &data); err != nil {
+		return fmt.Errorf("invalid email data: %w", err)
+	}
+
+	msg, err := w.renderer.Render(email.Kind, email.Language, email.Recipient, data)
+	if err != nil {
+		return err
+	}
+	email.Subject = msg.Subject
+
+	return w.sender.Send(msg)
+}
+
+// backoff задержка перед следующей попыткой после attempts неудачных
+func (w *Worker) backoff(attempts int) time.Duration {
+	delay := w.config.BaseBackoff
+	for i := 1; i < attempts && delay < w.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > w.config.MaxBackoff {
+		delay = w.config.MaxBackoff
+	}
+	return delay
+}
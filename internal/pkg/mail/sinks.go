@@ -0,0 +1,53 @@
+package mail
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileSender складывает письма в каталог файлами .eml вместо отправки.
+// Подходит для локальной разработки: файлы открываются любым почтовым клиентом.
+type FileSender struct {
+	dir  string
+	from string
+}
+
+func NewFileSender(dir, from string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileSender{dir: dir, from: from}, nil
+}
+
+func (s *FileSender) Send(msg *Message) error {
+	data, err := msg.Bytes(s.from)
+	if err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405.000000"), hex.EncodeToString(suffix))
+
+	// Пишем во временный файл и переименовываем, чтобы читатель каталога
+	// не увидел недописанное письмо
+	tmp := filepath.Join(s.dir, "."+name+".tmp")
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, name))
+}
+
+// LogSender ничего не отправляет, только пишет в лог получателя и тему.
+// Позволяет запустить сервер без почтового сервера.
+type LogSender struct{}
+
+func (LogSender) Send(msg *Message) error {
+	log.Printf("mail: skipped sending %q to %s (log transport)", msg.Subject, msg.To)
+	return nil
+}
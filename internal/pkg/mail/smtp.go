@@ -0,0 +1,153 @@
+package mail
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"time"
+)
+
+// Sender доставляет одно собранное письмо
+type Sender interface {
+	Send(msg *Message) error
+}
+
+// Режимы шифрования SMTP
+const (
+	// Соединение без TLS переключается командой STARTTLS, обычно порт 587
+	TLSStartTLS = "starttls"
+	// TLS с первого байта, обычно порт 465
+	TLSImplicit = "implicit"
+	// Без шифрования, только для локальных серверов
+	TLSNone = "none"
+)
+
+// SMTPConfig параметры подключения к почтовому серверу
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	// Адрес отправителя, по умолчанию Username. Можно с именем: "Cyber Chase <noreply@example.com>"
+	From    string
+	TLS     string
+	Timeout time.Duration
+}
+
+type SMTPMailer struct {
+	config SMTPConfig
+}
+
+func NewSMTPMailer(config SMTPConfig) *SMTPMailer {
+	if config.From == "" {
+		config.From = config.Username
+	}
+	if config.TLS == "" {
+		config.TLS = TLSStartTLS
+		if config.Port == "465" {
+			config.TLS = TLSImplicit
+		}
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	return &SMTPMailer{config: config}
+}
+
+func (m *SMTPMailer) Send(msg *Message) error {
+	from, err := parseFrom(m.config.From)
+	if err != nil {
+		return err
+	}
+	data, err := msg.Bytes(from.String())
+	if err != nil {
+		return err
+	}
+
+	client, err := m.dial()
+	if err != nil {
+		return fmt.Errorf("smtp connect: %w", err)
+	}
+	defer client.Close()
+
+	if m.config.Username != "" {
+		auth := smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("smtp MAIL FROM: %w", err)
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return fmt.Errorf("smtp RCPT TO: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("smtp write: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// parseFrom разбирает адрес отправителя: в MAIL FROM уходит только сам адрес без имени
+func parseFrom(from string) (*netmail.Address, error) {
+	addr, err := netmail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %w", from, err)
+	}
+	return addr, nil
+}
+
+// dial открывает соединение с учетом режима TLS. Таймаут действует на весь
+// разговор с сервером, чтобы зависший сервер не блокировал отправку очереди.
+func (m *SMTPMailer) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(m.config.Host, m.config.Port)
+	dialer := &net.Dialer{Timeout: m.config.Timeout}
+	tlsConfig := &tls.Config{ServerName: m.config.Host, MinVersion: tls.VersionTLS12}
+
+	var conn net.Conn
+	var err error
+	if m.config.TLS == TLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Now().Add(m.config.Timeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if m.config.TLS == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, errors.New("server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
+	return client, nil
+}
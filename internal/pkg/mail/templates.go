@@ -0,0 +1,136 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var defaultTemplates embed.FS
+
+// Языки, для которых есть встроенные шаблоны
+var Languages = []string{"ru", "en"}
+
+// DefaultLanguage язык писем, если у получателя он не указан или не поддерживается
+const DefaultLanguage = "ru"
+
+// NormalizeLanguage приводит код языка вида "en-US" к поддерживаемому, иначе DefaultLanguage
+func NormalizeLanguage(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	for _, supported := range Languages {
+		if lang == supported {
+			return lang
+		}
+	}
+	return DefaultLanguage
+}
+
+// Renderer собирает письма из шаблонов. Для каждого вида письма на каждом языке
+// нужен <lang>/<kind>.txt с блоком {{define "subject"}} и текстом письма,
+// <lang>/<kind>.html необязателен. Файлы из каталога переопределения имеют
+// приоритет над встроенными, поэтому менять тексты можно без пересборки.
+type Renderer struct {
+	sources []fs.FS
+}
+
+// NewRenderer создает сборщик писем, dir - каталог переопределения шаблонов, может быть пустым
+func NewRenderer(dir string) (*Renderer, error) {
+	embedded, err := fs.Sub(defaultTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Renderer{sources: []fs.FS{embedded}}
+	if dir != "" {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, fmt.Errorf("mail template dir: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("mail template dir %s is not a directory", dir)
+		}
+		r.sources = append([]fs.FS{os.DirFS(dir)}, r.sources...)
+	}
+	return r, nil
+}
+
+// Render собирает письмо вида kind на языке lang, при отсутствии шаблона
+// на этом языке используется DefaultLanguage
+func (r *Renderer) Render(kind, lang, to string, data interface{}) (*Message, error) {
+	lang = NormalizeLanguage(lang)
+
+	text, err := r.read(lang, kind+".txt")
+	if errors.Is(err, fs.ErrNotExist) && lang != DefaultLanguage {
+		lang = DefaultLanguage
+		text, err = r.read(lang, kind+".txt")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("template %s/%s: %w", lang, kind, err)
+	}
+
+	textTmpl, err := texttemplate.New(kind).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if textTmpl.Lookup("subject") == nil {
+		return nil, fmt.Errorf("template %s/%s.txt has no subject block", lang, kind)
+	}
+
+	msg := &Message{To: to}
+	var buf bytes.Buffer
+	if err := textTmpl.ExecuteTemplate(&buf, "subject", data); err != nil {
+		return nil, err
+	}
+	msg.Subject = strings.TrimSpace(buf.String())
+
+	buf.Reset()
+	if err := textTmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	msg.Text = strings.TrimSpace(buf.String()) + "\n"
+
+	html, err := r.read(lang, kind+".html")
+	if errors.Is(err, fs.ErrNotExist) {
+		return msg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	htmlTmpl, err := htmltemplate.New(kind).Parse(html)
+	if err != nil {
+		return nil, err
+	}
+	buf.Reset()
+	if err := htmlTmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	msg.HTML = buf.String()
+
+	return msg, nil
+}
+
+// read ищет файл шаблона сначала в каталоге переопределения, потом во встроенных
+func (r *Renderer) read(lang, name string) (string, error) {
+	for _, source := range r.sources {
+		data, err := fs.ReadFile(source, path.Join(lang, name))
+		if err == nil {
+			return string(data), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", fs.ErrNotExist
+}
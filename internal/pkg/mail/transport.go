@@ -0,0 +1,90 @@
+package mail
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Способы доставки писем
+const (
+	TransportSMTP = "smtp"
+	TransportFile = "file"
+	TransportLog  = "log"
+)
+
+// TransportConfig выбор и настройки способа доставки
+type TransportConfig struct {
+	Transport string
+	SMTP      SMTPConfig
+	// Каталог для .eml при TransportFile
+	FileDir string
+}
+
+// LoadTransportConfig читает настройки из окружения. Если MAIL_TRANSPORT не задан,
+// используется SMTP при заданном SMTP_HOST и лог иначе. Письма в лог считаются
+// отправленными, поэтому без явного MAIL_TRANSPORT=log об этом предупреждается при запуске.
+func LoadTransportConfig() TransportConfig {
+	config := TransportConfig{
+		Transport: strings.ToLower(os.Getenv("MAIL_TRANSPORT")),
+		SMTP: SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USER"),
+			Password: os.Getenv("SMTP_PASS"),
+			From:     os.Getenv("SMTP_FROM"),
+			TLS:      strings.ToLower(os.Getenv("SMTP_TLS")),
+			Timeout:  envDuration("SMTP_TIMEOUT", 10*time.Second),
+		},
+		FileDir: os.Getenv("MAIL_FILE_DIR"),
+	}
+
+	if config.Transport == "" {
+		config.Transport = TransportLog
+		if config.SMTP.Host != "" {
+			config.Transport = TransportSMTP
+		} else {
+			log.Printf("WARNING: neither MAIL_TRANSPORT nor SMTP_HOST is set, emails are NOT delivered and only written to the log. " +
+				"Temporary passwords and reset codes will be lost. Configure SMTP or set MAIL_TRANSPORT=log explicitly")
+		}
+	}
+	if config.FileDir == "" {
+		config.FileDir = "mail-out"
+	}
+	return config
+}
+
+// NewSender создает отправителя по настройкам
+func NewSender(config TransportConfig) (Sender, error) {
+	from := config.SMTP.From
+	if from == "" {
+		from = config.SMTP.Username
+	}
+	if from == "" {
+		from = "noreply@localhost"
+	}
+
+	switch config.Transport {
+	case TransportSMTP:
+		if config.SMTP.Host == "" || config.SMTP.Port == "" {
+			return nil, fmt.Errorf("smtp transport requires SMTP_HOST and SMTP_PORT")
+		}
+		switch config.SMTP.TLS {
+		case "", TLSStartTLS, TLSImplicit, TLSNone:
+		default:
+			return nil, fmt.Errorf("unknown SMTP_TLS %q, expected starttls, implicit or none", config.SMTP.TLS)
+		}
+		if _, err := parseFrom(from); err != nil {
+			return nil, fmt.Errorf("SMTP_FROM: %w", err)
+		}
+		return NewSMTPMailer(config.SMTP), nil
+	case TransportFile:
+		return NewFileSender(config.FileDir, from)
+	case TransportLog:
+		return LogSender{}, nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_TRANSPORT %q, expected smtp, file or log", config.Transport)
+	}
+}
@@ -1,34 +1,90 @@
 package pkg
 
 import (
+	"Cyber-chase/internal/models"
+	"Cyber-chase/internal/pkg/auth"
+	"errors"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 	"net/http"
 	"strings"
 )
 
-func JWTAuthMiddleware(secret string) gin.HandlerFunc {
+// claimsKey ключ, под которым проверенные claims лежат в контексте запроса
+const claimsKey = "authClaims"
+
+// Policy описывает, кому доступна группа маршрутов или отдельный маршрут
+type Policy struct {
+	// Допустимые роли токена, хотя бы одна обязательна
+	Roles []string
+	// Допустимые роли сотрудников компании, пусто - любые
+	CompanyRoles []string
+	// Маршруты, доступные компании, которая еще не сменила временный пароль
+	ResetAllowedPaths []string
+}
+
+var (
+	AdminPolicy   = Policy{Roles: []string{auth.RoleAdmin}}
+	TeamPolicy    = Policy{Roles: []string{auth.RoleTeam}}
+	CompanyPolicy = Policy{
+		Roles:             []string{auth.RoleCompany},
+		ResetAllowedPaths: []string{"/api/v1/company/change-password"},
+	}
+)
+
+// CompanyRolePolicy пускает только сотрудников компании с указанными ролями
+func CompanyRolePolicy(roles ...string) Policy {
+	policy := CompanyPolicy
+	policy.CompanyRoles = roles
+	return policy
+}
+
+// Authorize проверяет токен и его соответствие политике. Если токен уже проверен
+// middleware группы, повторно он не разбирается, проверяется только политика.
+func Authorize(tokens *auth.Manager, policy Policy) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			return
+		claims := Claims(c)
+		if claims == nil {
+			authHeader := c.GetHeader("Authorization")
+			if authHeader == "" {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+				return
+			}
+
+			var err error
+			claims, err = tokens.Verify(c.Request.Context(), strings.TrimPrefix(authHeader, "Bearer "))
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+				return
+			}
+
+			if _, err := claims.SubjectID(); err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token subject"})
+				return
+			}
+			if claims.Role == auth.RoleCompany && claims.CompanyRole == "" {
+				// Токены основной учетной записи компании дают права владельца
+				claims.CompanyRole = models.CompanyRoleOwner
+			}
+
+			c.Set(claimsKey, claims)
 		}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
-		})
+		if !contains(policy.Roles, claims.Role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
 
-		if err != nil || !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		if claims.Role == auth.RoleCompany && len(policy.CompanyRoles) > 0 && !contains(policy.CompanyRoles, claims.CompanyRole) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient company role"})
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok || claims["role"] != "admin" {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		if claims.ResetRequired && !contains(policy.ResetAllowedPaths, c.Request.URL.Path) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "Password reset required",
+				"code":  "PASSWORD_RESET_REQUIRED",
+			})
 			return
 		}
 
@@ -36,83 +92,106 @@ func JWTAuthMiddleware(secret string) gin.HandlerFunc {
 	}
 }
 
-func CompanyAuthMiddleware(secret string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			return
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
 		}
+	}
+	return false
+}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
-		})
-
-		if err != nil || !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			return
-		}
+// Claims возвращает проверенные claims текущего запроса или nil
+func Claims(c *gin.Context) *auth.Claims {
+	value, ok := c.Get(claimsKey)
+	if !ok {
+		return nil
+	}
+	claims, _ := value.(*auth.Claims)
+	return claims
+}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok || claims["role"] != "company" {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access denied"})
-			return
-		}
+// subjectID возвращает ID владельца токена, если у токена нужная роль
+func subjectID(c *gin.Context, role string) uuid.UUID {
+	claims := Claims(c)
+	if claims == nil || claims.Role != role {
+		return uuid.Nil
+	}
+	id, err := claims.SubjectID()
+	if err != nil {
+		return uuid.Nil
+	}
+	return id
+}
 
-		companyID, err := uuid.Parse(claims["sub"].(string))
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid company ID"})
-			return
-		}
+// AdminID возвращает ID администратора, uuid.Nil для других ролей
+func AdminID(c *gin.Context) uuid.UUID {
+	return subjectID(c, auth.RoleAdmin)
+}
 
-		c.Set("companyID", companyID.String())
+// AdminUsername возвращает логин администратора для журналов
+func AdminUsername(c *gin.Context) string {
+	if claims := Claims(c); claims != nil && claims.Role == auth.RoleAdmin {
+		return claims.Username
+	}
+	return ""
+}
 
-		if resetRequired, exists := claims["reset_required"]; exists && resetRequired.(bool) {
-			if c.Request.URL.Path != "/api/v1/company/change-password" {
-				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
-					"error": "Password reset required",
-					"code":  "PASSWORD_RESET_REQUIRED",
-				})
-				return
-			}
-		}
+// CompanyID возвращает ID компании, uuid.Nil для других ролей
+func CompanyID(c *gin.Context) uuid.UUID {
+	return subjectID(c, auth.RoleCompany)
+}
 
-		c.Next()
+// CompanyUserID возвращает ID сотрудника компании, uuid.Nil для основной учетной записи
+func CompanyUserID(c *gin.Context) uuid.UUID {
+	claims := Claims(c)
+	if claims == nil || claims.Role != auth.RoleCompany || claims.UserID == "" {
+		return uuid.Nil
+	}
+	id, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return uuid.Nil
 	}
+	return id
 }
 
-func TeamAuthMiddleware(secret string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Требуется авторизация"})
-			return
-		}
+// CompanyRole возвращает роль сотрудника компании
+func CompanyRole(c *gin.Context) string {
+	if claims := Claims(c); claims != nil && claims.Role == auth.RoleCompany {
+		return claims.CompanyRole
+	}
+	return ""
+}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
-		})
+// TeamID возвращает ID команды, uuid.Nil для других ролей
+func TeamID(c *gin.Context) uuid.UUID {
+	return subjectID(c, auth.RoleTeam)
+}
 
-		if err != nil || !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Неверный токен"})
-			return
-		}
+// serviceKeyKey ключ, под которым проверенный ключ сервиса лежит в контексте запроса
+const serviceKeyKey = "serviceKey"
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok || claims["role"] != "team" {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Доступ запрещен"})
+// RequireServiceKey пускает вызовы внутреннего API с ключом из заголовка
+// X-API-Key, у которого есть разрешение scope. Токены пользователей не принимаются.
+func RequireServiceKey(store auth.ServiceKeyStore, scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("X-API-Key")
+		if key == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "X-API-Key header required"})
 			return
 		}
 
-		teamID, err := uuid.Parse(claims["sub"].(string))
+		serviceKey, err := auth.VerifyServiceKey(c.Request.Context(), store, key, scope)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Неверный ID команды"})
+			if errors.Is(err, auth.ErrScopeDenied) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Service key lacks scope " + scope})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid service key"})
 			return
 		}
 
-		c.Set("teamID", teamID.String())
+		c.Set(serviceKeyKey, serviceKey)
 		c.Next()
 	}
 }
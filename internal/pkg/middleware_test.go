@@ -0,0 +1,259 @@
+package pkg
+
+import (
+	"Cyber-chase/internal/models"
+	"Cyber-chase/internal/pkg/auth"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+// fakeTokenStore хранит версии токенов в памяти, refresh-токены в этих тестах не нужны
+type fakeTokenStore struct {
+	versions map[string]int
+}
+
+func (s *fakeTokenStore) TokenVersion(_ context.Context, role, subjectID, userID string) (int, error) {
+	version, ok := s.versions[role+":"+subjectID+":"+userID]
+	if !ok {
+		return 0, errors.New("not found")
+	}
+	return version, nil
+}
+
+func (s *fakeTokenStore) SaveRefreshToken(context.Context, *models.RefreshToken) error {
+	return nil
+}
+
+func (s *fakeTokenStore) FindRefreshToken(context.Context, string) (*models.RefreshToken, error) {
+	return nil, errors.New("not found")
+}
+
+func (s *fakeTokenStore) RevokeRefreshToken(context.Context, uuid.UUID) (bool, error) {
+	return true, nil
+}
+
+const (
+	testSecret         = "test-secret"
+	changePasswordPath = "/api/v1/company/change-password"
+	companyTasksPath   = "/api/v1/company/tasks"
+)
+
+func TestAuthorize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	adminID := uuid.NewString()
+	teamID := uuid.NewString()
+	companyID := uuid.NewString()
+	operatorID := uuid.NewString()
+	viewerID := uuid.NewString()
+	staleTeamID := uuid.NewString()
+
+	store := &fakeTokenStore{versions: map[string]int{
+		auth.RoleAdmin + ":" + adminID + ":":                  0,
+		auth.RoleTeam + ":" + teamID + ":":                    0,
+		auth.RoleCompany + ":" + companyID + ":":              0,
+		auth.RoleCompany + ":" + companyID + ":" + operatorID: 0,
+		auth.RoleCompany + ":" + companyID + ":" + viewerID:   0,
+		auth.RoleTeam + ":" + staleTeamID + ":":               2,
+	}}
+	tokens := auth.NewManager(testSecret, time.Minute, time.Hour, store)
+
+	issue := func(manager *auth.Manager, claims auth.Claims) string {
+		t.Helper()
+		token, err := manager.IssueAccessToken(claims)
+		if err != nil {
+			t.Fatalf("issue token: %v", err)
+		}
+		return token
+	}
+	subject := func(id string) jwt.RegisteredClaims {
+		return jwt.RegisteredClaims{Subject: id}
+	}
+
+	adminToken := issue(tokens, auth.Claims{Role: auth.RoleAdmin, Username: "root", RegisteredClaims: subject(adminID)})
+	teamToken := issue(tokens, auth.Claims{Role: auth.RoleTeam, RegisteredClaims: subject(teamID)})
+	ownerToken := issue(tokens, auth.Claims{Role: auth.RoleCompany, RegisteredClaims: subject(companyID)})
+	operatorToken := issue(tokens, auth.Claims{Role: auth.RoleCompany, CompanyRole: models.CompanyRoleOperator, UserID: operatorID, RegisteredClaims: subject(companyID)})
+	viewerToken := issue(tokens, auth.Claims{Role: auth.RoleCompany, CompanyRole: models.CompanyRoleViewer, UserID: viewerID, RegisteredClaims: subject(companyID)})
+	resetToken := issue(tokens, auth.Claims{Role: auth.RoleCompany, ResetRequired: true, RegisteredClaims: subject(companyID)})
+	resetTeamToken := issue(tokens, auth.Claims{Role: auth.RoleTeam, ResetRequired: true, RegisteredClaims: subject(teamID)})
+	staleToken := issue(tokens, auth.Claims{Role: auth.RoleTeam, TokenVersion: 1, RegisteredClaims: subject(staleTeamID)})
+	unknownToken := issue(tokens, auth.Claims{Role: auth.RoleTeam, RegisteredClaims: subject(uuid.NewString())})
+
+	expiredTokens := auth.NewManager(testSecret, -time.Minute, time.Hour, store)
+	expiredToken := issue(expiredTokens, auth.Claims{Role: auth.RoleAdmin, RegisteredClaims: subject(adminID)})
+
+	foreignTokens := auth.NewManager("another-secret", time.Minute, time.Hour, store)
+	foreignToken := issue(foreignTokens, auth.Claims{Role: auth.RoleAdmin, RegisteredClaims: subject(adminID)})
+
+	// Подпись остается от исходного токена, а роль в payload меняется на admin
+	tamperedToken := tamper(t, teamToken, auth.Claims{Role: auth.RoleAdmin, RegisteredClaims: subject(teamID)})
+
+	noneToken, err := jwt.NewWithClaims(jwt.SigningMethodNone, auth.Claims{Role: auth.RoleAdmin, RegisteredClaims: subject(adminID)}).
+		SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("sign none token: %v", err)
+	}
+
+	ownerOnly := CompanyRolePolicy(models.CompanyRoleOwner)
+	editors := CompanyRolePolicy(models.CompanyRoleOwner, models.CompanyRoleOperator)
+
+	tests := []struct {
+		name   string
+		policy Policy
+		path   string
+		header string
+		status int
+		code   string
+	}{
+		{name: "missing header", policy: AdminPolicy, header: "", status: http.StatusUnauthorized},
+		{name: "admin on admin routes", policy: AdminPolicy, header: "Bearer " + adminToken, status: http.StatusOK},
+		{name: "team on admin routes", policy: AdminPolicy, header: "Bearer " + teamToken, status: http.StatusForbidden},
+		{name: "company on admin routes", policy: AdminPolicy, header: "Bearer " + ownerToken, status: http.StatusForbidden},
+		{name: "team on team routes", policy: TeamPolicy, header: "Bearer " + teamToken, status: http.StatusOK},
+		{name: "admin on team routes", policy: TeamPolicy, header: "Bearer " + adminToken, status: http.StatusForbidden},
+		{name: "company on team routes", policy: TeamPolicy, header: "Bearer " + ownerToken, status: http.StatusForbidden},
+		{name: "company main account on company routes", policy: CompanyPolicy, header: "Bearer " + ownerToken, status: http.StatusOK},
+		{name: "viewer on company routes", policy: CompanyPolicy, header: "Bearer " + viewerToken, status: http.StatusOK},
+		{name: "team on company routes", policy: CompanyPolicy, header: "Bearer " + teamToken, status: http.StatusForbidden},
+		{name: "main account counts as owner", policy: ownerOnly, header: "Bearer " + ownerToken, status: http.StatusOK},
+		{name: "operator on owner routes", policy: ownerOnly, header: "Bearer " + operatorToken, status: http.StatusForbidden},
+		{name: "operator on editor routes", policy: editors, header: "Bearer " + operatorToken, status: http.StatusOK},
+		{name: "viewer on editor routes", policy: editors, header: "Bearer " + viewerToken, status: http.StatusForbidden},
+		{name: "reset required blocks other routes", policy: CompanyPolicy, path: companyTasksPath, header: "Bearer " + resetToken, status: http.StatusForbidden, code: "PASSWORD_RESET_REQUIRED"},
+		{name: "reset required allows change password", policy: CompanyPolicy, path: changePasswordPath, header: "Bearer " + resetToken, status: http.StatusOK},
+		{name: "reset required with empty allow-list", policy: TeamPolicy, path: changePasswordPath, header: "Bearer " + resetTeamToken, status: http.StatusForbidden, code: "PASSWORD_RESET_REQUIRED"},
+		{name: "expired token", policy: AdminPolicy, header: "Bearer " + expiredToken, status: http.StatusUnauthorized},
+		{name: "token signed with another secret", policy: AdminPolicy, header: "Bearer " + foreignToken, status: http.StatusUnauthorized},
+		{name: "tampered payload", policy: AdminPolicy, header: "Bearer " + tamperedToken, status: http.StatusUnauthorized},
+		{name: "unsigned token", policy: AdminPolicy, header: "Bearer " + noneToken, status: http.StatusUnauthorized},
+		{name: "outdated token version", policy: TeamPolicy, header: "Bearer " + staleToken, status: http.StatusUnauthorized},
+		{name: "deleted owner", policy: TeamPolicy, header: "Bearer " + unknownToken, status: http.StatusUnauthorized},
+		{name: "garbage token", policy: AdminPolicy, header: "Bearer not-a-jwt", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.path
+			if path == "" {
+				path = companyTasksPath
+			}
+
+			router := gin.New()
+			router.GET(path, Authorize(tokens, tt.policy), func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"company_role": CompanyRole(c)})
+			})
+
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if tt.code != "" && !containsJSONField(rec.Body.String(), "code", tt.code) {
+				t.Fatalf("body %s does not contain code %s", rec.Body.String(), tt.code)
+			}
+		})
+	}
+}
+
+func TestAuthorizeSetsAccessors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	companyID := uuid.New()
+	userID := uuid.New()
+	store := &fakeTokenStore{versions: map[string]int{
+		auth.RoleCompany + ":" + companyID.String() + ":":                   0,
+		auth.RoleCompany + ":" + companyID.String() + ":" + userID.String(): 0,
+	}}
+	tokens := auth.NewManager(testSecret, time.Minute, time.Hour, store)
+
+	tests := []struct {
+		name   string
+		claims auth.Claims
+		role   string
+		userID uuid.UUID
+	}{
+		{
+			name:   "main account",
+			claims: auth.Claims{Role: auth.RoleCompany, RegisteredClaims: jwt.RegisteredClaims{Subject: companyID.String()}},
+			role:   models.CompanyRoleOwner,
+			userID: uuid.Nil,
+		},
+		{
+			name: "staff account",
+			claims: auth.Claims{Role: auth.RoleCompany, CompanyRole: models.CompanyRoleViewer, UserID: userID.String(),
+				RegisteredClaims: jwt.RegisteredClaims{Subject: companyID.String()}},
+			role:   models.CompanyRoleViewer,
+			userID: userID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tokens.IssueAccessToken(tt.claims)
+			if err != nil {
+				t.Fatalf("issue token: %v", err)
+			}
+
+			router := gin.New()
+			router.GET(companyTasksPath, Authorize(tokens, CompanyPolicy), func(c *gin.Context) {
+				if got := CompanyID(c); got != companyID {
+					t.Errorf("CompanyID = %s, want %s", got, companyID)
+				}
+				if got := CompanyRole(c); got != tt.role {
+					t.Errorf("CompanyRole = %q, want %q", got, tt.role)
+				}
+				if got := CompanyUserID(c); got != tt.userID {
+					t.Errorf("CompanyUserID = %s, want %s", got, tt.userID)
+				}
+				if got := AdminID(c); got != uuid.Nil {
+					t.Errorf("AdminID = %s, want nil", got)
+				}
+				if got := TeamID(c); got != uuid.Nil {
+					t.Errorf("TeamID = %s, want nil", got)
+				}
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, companyTasksPath, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+// tamper подменяет payload токена, оставляя исходные заголовок и подпись
+func tamper(t *testing.T, token string, claims auth.Claims) string {
+	t.Helper()
+
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("unused"))
+	if err != nil {
+		t.Fatalf("sign forged token: %v", err)
+	}
+
+	original := strings.Split(token, ".")
+	return original[0] + "." + strings.Split(forged, ".")[1] + "." + original[2]
+}
+
+func containsJSONField(body, field, value string) bool {
+	return strings.Contains(body, `"`+field+`":"`+value+`"`)
+}
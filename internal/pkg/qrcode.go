@@ -0,0 +1,98 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/skip2/go-qrcode"
+)
+
+// QRPayloadPrefix marks bot deep link payloads that carry a QR task code
+const QRPayloadPrefix = "qr_"
+
+// Telegram allows up to 64 characters of [A-Za-z0-9_-] in a start payload
+var qrCodePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,61}$`)
+
+// GenerateQRCode returns a random code suitable for a QR answer task
+func GenerateQRCode() (string, error) {
+	const chars = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnpqrstuvwxyz23456789"
+	result := make([]byte, 16)
+
+	for i := range result {
+		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+		if err != nil {
+			return "", err
+		}
+		result[i] = chars[num.Int64()]
+	}
+
+	return string(result), nil
+}
+
+// IsValidQRCode reports whether the code fits into a bot deep link payload
+func IsValidQRCode(code string) bool {
+	return qrCodePattern.MatchString(code)
+}
+
+// QRDeepLink builds the t.me link that opens the bot with the task code
+func QRDeepLink(botUsername, code string) string {
+	return fmt.Sprintf("https://t.me/%s?start=%s%s", botUsername, QRPayloadPrefix, code)
+}
+
+// ParseQRPayload extracts the task code from a deep link start payload
+func ParseQRPayload(payload string) (string, bool) {
+	if !strings.HasPrefix(payload, QRPayloadPrefix) {
+		return "", false
+	}
+	code := strings.TrimPrefix(payload, QRPayloadPrefix)
+	if !IsValidQRCode(code) {
+		return "", false
+	}
+	return code, true
+}
+
+// QRCodePNG renders the content as a PNG QR code of the given size in pixels
+func QRCodePNG(content string, size int) ([]byte, error) {
+	png, err := qrcode.Encode(content, qrcode.Medium, size)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding QR code: %w", err)
+	}
+	return png, nil
+}
+
+// QRCodePDF renders the content as a printable A4 page with a QR code and
+// the caption below it. Built-in PDF fonts only cover Latin characters, so
+// the caption should not contain Cyrillic text.
+func QRCodePDF(content, caption string) ([]byte, error) {
+	png, err := QRCodePNG(content, 1024)
+	if err != nil {
+		return nil, err
+	}
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+
+	pageWidth, _ := pdf.GetPageSize()
+	const imageSize = 150.0
+
+	options := gofpdf.ImageOptions{ImageType: "PNG"}
+	pdf.RegisterImageOptionsReader("qr", options, bytes.NewReader(png))
+	pdf.ImageOptions("qr", (pageWidth-imageSize)/2, 40, imageSize, imageSize, false, options, 0, "")
+
+	pdf.SetY(40 + imageSize + 10)
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.CellFormat(0, 8, caption, "", 1, "C", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.CellFormat(0, 6, content, "", 1, "C", false, 0, content)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("error rendering PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
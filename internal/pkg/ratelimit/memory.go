@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+type memoryItem struct {
+	entry     Entry
+	expiresAt time.Time
+}
+
+// MemoryStore хранит счетчики в памяти процесса
+type MemoryStore struct {
+	mu     sync.Mutex
+	items  map[string]memoryItem
+	writes int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{items: make(map[string]memoryItem)}
+}
+
+func (s *MemoryStore) Get(key string) (Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[key]
+	if !ok {
+		return Entry{}, false
+	}
+	if time.Now().After(item.expiresAt) {
+		delete(s.items, key)
+		return Entry{}, false
+	}
+	return item.entry, true
+}
+
+func (s *MemoryStore) Set(key string, entry Entry, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.items[key] = memoryItem{entry: entry, expiresAt: time.Now().Add(ttl)}
+
+	// Периодически чистим устаревшие записи, чтобы карта не росла бесконечно
+	s.writes++
+	if s.writes%1000 == 0 {
+		now := time.Now()
+		for k, item := range s.items {
+			if now.After(item.expiresAt) {
+				delete(s.items, k)
+			}
+		}
+	}
+}
+
+func (s *MemoryStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.items, key)
+}
@@ -0,0 +1,224 @@
+package ratelimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Entry состояние счетчика для одного ключа
+type Entry struct {
+	// Попытки в текущем окне
+	Hits        int
+	WindowStart time.Time
+	// Сколько раз ключ уже блокировался, от этого зависит длительность следующей блокировки
+	Lockouts    int
+	LockedUntil time.Time
+}
+
+// Store хранилище счетчиков. Запись можно удалить по истечении ttl.
+type Store interface {
+	Get(key string) (Entry, bool)
+	Set(key string, entry Entry, ttl time.Duration)
+	Delete(key string)
+}
+
+// Config правила ограничения
+type Config struct {
+	// Сколько попыток допускается за окно до блокировки
+	MaxHits int
+	Window  time.Duration
+	// Первая блокировка, каждая следующая вдвое длиннее, но не больше MaxLockout
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+}
+
+var (
+	// LoginConfig защищает вход от перебора паролей
+	LoginConfig = Config{MaxHits: 5, Window: 15 * time.Minute, BaseLockout: time.Minute, MaxLockout: time.Hour}
+	// AnswerConfig ограничивает частоту ответов на задачу
+	AnswerConfig = Config{MaxHits: 5, Window: time.Minute, BaseLockout: 30 * time.Second, MaxLockout: 10 * time.Minute}
+	// ResetRequestConfig ограничивает запросы писем для сброса пароля
+	ResetRequestConfig = Config{MaxHits: 3, Window: 15 * time.Minute, BaseLockout: 15 * time.Minute, MaxLockout: 2 * time.Hour}
+)
+
+// Limiter считает попытки по ключам (IP, email, чат) и блокирует ключи с экспоненциально растущим временем
+type Limiter struct {
+	mu    sync.Mutex
+	store Store
+	cfg   Config
+}
+
+func NewLimiter(cfg Config, store Store) *Limiter {
+	return &Limiter{store: store, cfg: cfg}
+}
+
+// Allow проверяет, что ни один из ключей не заблокирован.
+// Если заблокирован, возвращает время до разблокировки.
+func (l *Limiter) Allow(keys ...string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	var wait time.Duration
+	for _, key := range keys {
+		entry, ok := l.store.Get(key)
+		if ok && entry.LockedUntil.After(now) {
+			if remaining := entry.LockedUntil.Sub(now); remaining > wait {
+				wait = remaining
+			}
+		}
+	}
+	return wait, wait == 0
+}
+
+// Hit засчитывает попытку по всем ключам: неудачный вход или очередной ответ
+func (l *Limiter) Hit(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for _, key := range keys {
+		entry, _ := l.store.Get(key)
+		if now.Sub(entry.WindowStart) > l.cfg.Window {
+			entry.Hits = 0
+			entry.WindowStart = now
+		}
+
+		entry.Hits++
+		if entry.Hits >= l.cfg.MaxHits {
+			entry.LockedUntil = now.Add(l.lockout(entry.Lockouts))
+			entry.Lockouts++
+			entry.Hits = 0
+			entry.WindowStart = now
+		}
+
+		// История блокировок хранится дольше самой блокировки, чтобы повторный перебор наказывался сильнее
+		l.store.Set(key, entry, l.cfg.MaxLockout+l.cfg.Window)
+	}
+}
+
+// Reset сбрасывает счетчики, например после успешного входа
+func (l *Limiter) Reset(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		l.store.Delete(key)
+	}
+}
+
+func (l *Limiter) lockout(previous int) time.Duration {
+	lockout := time.Duration(float64(l.cfg.BaseLockout) * math.Pow(2, float64(previous)))
+	if lockout <= 0 || lockout > l.cfg.MaxLockout {
+		return l.cfg.MaxLockout
+	}
+	return lockout
+}
+
+// Key собирает ключ счетчика, например Key("login", "email", email)
+func Key(parts ...string) string {
+	return strings.ToLower(strings.Join(parts, ":"))
+}
+
+// Abort отвечает 429 с заголовком Retry-After
+func Abort(c *gin.Context, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	c.Header("Retry-After", fmt.Sprint(seconds))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Too many attempts, try again later",
+		"code":        "RATE_LIMITED",
+		"retry_after": seconds,
+	})
+}
+
+// LoginGuard ограничивает вход по IP клиента и по логину из поля field тела запроса.
+// Ответ 401 засчитывается как неудачная попытка, успешный вход сбрасывает счетчик логина.
+func LoginGuard(l *Limiter, scope, field string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys, loginKey, ok := guardKeys(c, scope, field)
+		if !ok {
+			return
+		}
+
+		if wait, ok := l.Allow(keys...); !ok {
+			Abort(c, wait)
+			return
+		}
+
+		c.Next()
+
+		switch c.Writer.Status() {
+		case http.StatusUnauthorized:
+			l.Hit(keys...)
+		case http.StatusOK:
+			if loginKey != "" {
+				l.Reset(loginKey)
+			}
+		}
+	}
+}
+
+// RequestGuard ограничивает частоту запросов, которые всегда отвечают успехом
+// (например, отправку письма для сброса пароля): засчитывается каждый запрос.
+func RequestGuard(l *Limiter, scope, field string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys, _, ok := guardKeys(c, scope, field)
+		if !ok {
+			return
+		}
+
+		if wait, ok := l.Allow(keys...); !ok {
+			Abort(c, wait)
+			return
+		}
+		l.Hit(keys...)
+
+		c.Next()
+	}
+}
+
+// TrustedProxiesFromEnv возвращает адреса и подсети прокси из TRUSTED_PROXIES через запятую.
+// Только от них gin принимает X-Forwarded-For, иначе IP клиента в ключах счетчиков можно подменить заголовком.
+// Пустая переменная дает nil: прокси не доверяем и считаем попытки по адресу соединения.
+func TrustedProxiesFromEnv() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// guardKeys возвращает ключи счетчиков по IP и по логину из поля field, тело запроса сохраняется для обработчика.
+// IP берется из c.ClientIP(), поэтому роутер должен быть настроен через SetTrustedProxies(TrustedProxiesFromEnv()).
+func guardKeys(c *gin.Context, scope, field string) ([]string, string, bool) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return nil, "", false
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var payload map[string]interface{}
+	_ = json.Unmarshal(body, &payload)
+	login, _ := payload[field].(string)
+
+	keys := []string{Key(scope, "ip", c.ClientIP())}
+	loginKey := ""
+	if login != "" {
+		loginKey = Key(scope, field, strings.TrimSpace(login))
+		keys = append(keys, loginKey)
+	}
+	return keys, loginKey, true
+}
@@ -0,0 +1,111 @@
+package pkg
+
+import (
+	"Cyber-chase/internal/models"
+	"Cyber-chase/internal/pkg/auth"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeServiceKeyStore хранит ключи в памяти и считает обращения к хранилищу
+type fakeServiceKeyStore struct {
+	keys    []*models.ServiceKey
+	lookups int
+}
+
+func (s *fakeServiceKeyStore) FindServiceKeyByHash(_ context.Context, hash string) (*models.ServiceKey, error) {
+	s.lookups++
+	for _, key := range s.keys {
+		if key.KeyHash == hash {
+			return key, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (s *fakeServiceKeyStore) TouchServiceKey(context.Context, uuid.UUID, time.Time) error {
+	return nil
+}
+
+func TestRequireServiceKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &fakeServiceKeyStore{}
+	issue := func(name string, scopes ...string) (*models.ServiceKey, string) {
+		t.Helper()
+		stored, key, err := auth.NewServiceKey(name, "root", scopes)
+		if err != nil {
+			t.Fatalf("issue service key: %v", err)
+		}
+		stored.ID = uuid.New()
+		store.keys = append(store.keys, stored)
+		return stored, key
+	}
+
+	leaderboard, leaderboardKey := issue("scoreboard", auth.ScopeLeaderboardRead)
+	_, teamsKey := issue("crm", auth.ScopeTeamsRead)
+	revoked, revokedKey := issue("old", auth.ScopeLeaderboardRead)
+	revokedAt := time.Now()
+	revoked.RevokedAt = &revokedAt
+
+	companyHash, err := bcrypt.GenerateFromPassword([]byte("company-password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	tokens := auth.NewManager(testSecret, time.Minute, time.Hour, &fakeTokenStore{})
+	adminToken, err := tokens.IssueAccessToken(auth.Claims{Role: auth.RoleAdmin})
+	if err != nil {
+		t.Fatalf("issue token: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		key    string
+		status int
+		// Ключ без префикса отклоняется до обращения к хранилищу
+		noLookup bool
+	}{
+		{name: "key with scope", key: leaderboardKey, status: http.StatusOK},
+		{name: "missing header", key: "", status: http.StatusUnauthorized, noLookup: true},
+		{name: "key without scope", key: teamsKey, status: http.StatusForbidden},
+		{name: "revoked key", key: revokedKey, status: http.StatusUnauthorized},
+		{name: "unknown key", key: "ccsk_" + strings.Repeat("0", 64), status: http.StatusUnauthorized},
+		{name: "key without prefix", key: strings.TrimPrefix(leaderboardKey, "ccsk_"), status: http.StatusUnauthorized, noLookup: true},
+		{name: "stored key hash", key: leaderboard.KeyHash, status: http.StatusUnauthorized, noLookup: true},
+		{name: "company password hash", key: string(companyHash), status: http.StatusUnauthorized, noLookup: true},
+		{name: "user token", key: adminToken, status: http.StatusUnauthorized, noLookup: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/internal/leaderboard", RequireServiceKey(store, auth.ScopeLeaderboardRead), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/internal/leaderboard", nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			rec := httptest.NewRecorder()
+			lookups := store.lookups
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if tt.noLookup && store.lookups != lookups {
+				t.Fatal("key without the service prefix reached the store")
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"Cyber-chase/internal/models"
+	"Cyber-chase/internal/pkg/audit"
+	"context"
+)
+
+// CreateAuditLog добавляет запись в журнал действий, реализует audit.Store
+func (r *Repository) CreateAuditLog(ctx context.Context, entry *models.AuditLog) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+// FindAuditLogs возвращает записи журнала по фильтру, новые первыми
+func (r *Repository) FindAuditLogs(ctx context.Context, filter audit.Filter) ([]models.AuditLog, error) {
+	var entries []models.AuditLog
+	query := r.db.WithContext(ctx).Model(&models.AuditLog{})
+
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.CompanyID != nil {
+		query = query.Where("company_id = ?", *filter.CompanyID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	err := query.Order("created_at desc").Find(&entries).Error
+	return entries, err
+}
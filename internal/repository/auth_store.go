@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"Cyber-chase/internal/models"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TokenVersion возвращает текущую версию токенов владельца, реализует auth.Store
+func (r *Repository) TokenVersion(ctx context.Context, role, subjectID, userID string) (int, error) {
+	var versions []int
+	query := r.db.WithContext(ctx)
+
+	switch {
+	case role == "admin":
+		query = query.Model(&models.Admin{}).Where("id = ?", subjectID)
+	case role == "company" && userID != "":
+		query = query.Model(&models.CompanyUser{}).Where("id = ? AND company_id = ?", userID, subjectID)
+	case role == "company":
+		query = query.Model(&models.Company{}).Where("id = ?", subjectID)
+	case role == "team":
+		query = query.Model(&models.Team{}).Where("id = ?", subjectID)
+	default:
+		return 0, errors.New("unknown token role")
+	}
+
+	if err := query.Limit(1).Pluck("token_version", &versions).Error; err != nil {
+		return 0, err
+	}
+	if len(versions) == 0 {
+		return 0, ErrNotFound
+	}
+	return versions[0], nil
+}
+
+func (r *Repository) SaveRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *Repository) FindRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.WithContext(ctx).First(&token, "token_hash = ?", tokenHash).Error
+	return &token, err
+}
+
+func (r *Repository) RevokeRefreshToken(ctx context.Context, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"Cyber-chase/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// PrepareSchema готовит данные к AutoMigrate там, где новая схема их иначе не примет.
+// Вызывается до AutoMigrate, каждый шаг выполняется только один раз.
+func PrepareSchema(db *gorm.DB) error {
+	// Уникальный индекс отметок не создастся, пока в сессии есть повторные отметки одного типа,
+	// поэтому перед его созданием дубликаты схлопываются до самой ранней отметки
+	if db.Migrator().HasTable(&models.AnswerFlag{}) && !db.Migrator().HasIndex(&models.AnswerFlag{}, "idx_answer_flags_session_kind") {
+		if err := db.Exec(`DELETE FROM answer_flags a USING answer_flags b
+			WHERE a.session_id = b.session_id AND a.kind = b.kind
+			AND (a.created_at, a.id) > (b.created_at, b.id)`).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateLegacyData приводит к текущей схеме данные, записанные старыми версиями.
+// Вызывается после AutoMigrate, каждый шаг можно безопасно выполнять повторно.
+func MigrateLegacyData(db *gorm.DB) error {
+	// Раньше непривязанная команда хранила telegram_id = 0, теперь это NULL
+	if err := db.Exec("UPDATE teams SET telegram_id = NULL WHERE telegram_id = 0").Error; err != nil {
+		return err
+	}
+
+	// Коды приглашений сотрудников хранились открытым текстом, переносим их хеши и удаляем столбец
+	if db.Migrator().HasColumn(&models.CompanyUser{}, "invite_code") {
+		if err := db.Exec(`UPDATE company_users
+			SET invite_code_hash = encode(sha256(convert_to(invite_code, 'UTF8')), 'hex')
+			WHERE invite_code IS NOT NULL AND invite_code_hash IS NULL`).Error; err != nil {
+			return err
+		}
+		if err := db.Migrator().DropColumn(&models.CompanyUser{}, "invite_code"); err != nil {
+			return err
+		}
+	}
+
+	// Недоставленные письма с паролями и кодами раньше хранили данные бессрочно
+	if err := db.Exec(`UPDATE outbox_emails SET data = ''
+		WHERE status = ? AND kind NOT IN ('contest_reminder', 'contest_results')`, models.OutboxDead).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
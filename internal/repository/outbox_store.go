@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"Cyber-chase/internal/models"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// withEmail сохраняет изменения и письмо о них в одной транзакции:
+// письмо уходит в очередь, только если сохранение прошло
+func withEmail(db *gorm.DB, email *models.OutboxEmail, save func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := save(tx); err != nil {
+			return err
+		}
+		if email.NextAttemptAt.IsZero() {
+			email.NextAttemptAt = time.Now()
+		}
+		return tx.Create(email).Error
+	})
+}
+
+func (r *Repository) CreateCompanyWithEmail(ctx context.Context, company *models.Company, email *models.OutboxEmail) error {
+	return withEmail(r.db.WithContext(ctx), email, func(tx *gorm.DB) error {
+		return tx.Create(company).Error
+	})
+}
+
+func (r *Repository) UpdateCompanyWithEmail(ctx context.Context, company *models.Company, email *models.OutboxEmail) error {
+	return withEmail(r.db.WithContext(ctx), email, func(tx *gorm.DB) error {
+		return tx.Save(company).Error
+	})
+}
+
+func (r *Repository) CreateCompanyUserWithEmail(ctx context.Context, user *models.CompanyUser, email *models.OutboxEmail) error {
+	return withEmail(r.db.WithContext(ctx), email, func(tx *gorm.DB) error {
+		return tx.Create(user).Error
+	})
+}
+
+// ClaimDueEmails выбирает письма, которые пора отправить, и откладывает их на lease,
+// чтобы другой экземпляр сервера не взял те же письма
+func (r *Repository) ClaimDueEmails(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEmail, error) {
+	var emails []models.OutboxEmail
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.OutboxPending, time.Now()).
+			Order("next_attempt_at asc").
+			Limit(limit).
+			Find(&emails).Error; err != nil {
+			return err
+		}
+		if len(emails) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, 0, len(emails))
+		for _, email := range emails {
+			ids = append(ids, email.ID)
+		}
+		return tx.Model(&models.OutboxEmail{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", time.Now().Add(lease)).Error
+	})
+	return emails, err
+}
+
+func (r *Repository) UpdateOutboxEmail(ctx context.Context, email *models.OutboxEmail) error {
+	return r.db.WithContext(ctx).Save(email).Error
+}
+
+func (r *Repository) GetOutboxEmailByID(ctx context.Context, id uuid.UUID) (*models.OutboxEmail, error) {
+	var email models.OutboxEmail
+	err := r.db.WithContext(ctx).First(&email, "id = ?", id).Error
+	return &email, err
+}
+
+// RequeueDeadEmail возвращает dead письмо в очередь одним условным UPDATE, чтобы не задеть
+// письмо, которое сейчас отправляет воркер. false - письмо не dead или его данные стерты
+func (r *Repository) RequeueDeadEmail(ctx context.Context, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.OutboxEmail{}).
+		Where("id = ? AND status = ? AND data <> ''", id, models.OutboxDead).
+		Updates(map[string]interface{}{
+			"status":          models.OutboxPending,
+			"attempts":        0,
+			"last_error":      "",
+			"next_attempt_at": time.Now(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetOutboxEmails возвращает письма с указанным статусом, пустой статус - все
+func (r *Repository) GetOutboxEmails(ctx context.Context, status string, limit int) ([]models.OutboxEmail, error) {
+	var emails []models.OutboxEmail
+	query := r.db.WithContext(ctx).Order("created_at desc").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&emails).Error
+	return emails, err
+}
+
+// EnqueueEmails ставит письма в очередь одной вставкой и возвращает, сколько добавлено.
+// Письма рассылок контеста, которые уже есть в очереди для того же адреса, пропускаются
+func (r *Repository) EnqueueEmails(ctx context.Context, emails []*models.OutboxEmail) (int, error) {
+	if len(emails) == 0 {
+		return 0, nil
+	}
+	now := time.Now()
+	for _, email := range emails {
+		if email.NextAttemptAt.IsZero() {
+			email.NextAttemptAt = now
+		}
+	}
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&emails)
+	return int(result.RowsAffected), result.Error
+}
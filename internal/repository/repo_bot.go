@@ -6,28 +6,94 @@ import (
 	"fmt"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"log"
+	"time"
 )
 
+// TeamFilter условия отбора команд, пустые поля не учитываются
+type TeamFilter struct {
+	ContestID *uuid.UUID
+	CompanyID *uuid.UUID
+	Status    string
+}
+
 // TeamRepository интерфейс для работы с командами
 type TeamRepository interface {
 	Create(team *models.Team) error
+	CreateWithEmail(team *models.Team, email *models.OutboxEmail) error
 	FindByID(id uuid.UUID) (*models.Team, error)
 	FindByEmail(email string) (*models.Team, error)
 	FindByTelegramID(telegramID int64) (*models.Team, error)
 	Update(team *models.Team) error
+	UpdateWithEmail(team *models.Team, email *models.OutboxEmail) error
 	Delete(id uuid.UUID) error
 	SaveAnswer(answer *models.TeamAnswer) error
 	GetActiveContest() (*models.Contest, error)
 	GetTaskForTeam(teamID uuid.UUID, contestID uuid.UUID) (*models.Task, error)
-	GetUnassignedTeams() ([]models.Team, error)
-	ApproveTeam(teamID, companyID uuid.UUID) error
 	CreateTaskSession(session *models.TeamTaskSession) error
 	GetTaskSession(teamID, taskID uuid.UUID) (*models.TeamTaskSession, error)
 	UpdateTaskSession(session *models.TeamTaskSession) error
 	GetUsedTaskIDs(teamID uuid.UUID) ([]uuid.UUID, error)
+	GetTaskSessionByID(id uuid.UUID) (*models.TeamTaskSession, error)
+	CreateSubmission(submission *models.TeamSubmission) error
+	GetSubmissionByID(id uuid.UUID) (*models.TeamSubmission, error)
+	GetPendingSubmissionBySession(sessionID uuid.UUID) (*models.TeamSubmission, error)
+	GetSubmissionsByCompany(companyID uuid.UUID, status string) ([]models.TeamSubmission, error)
+	UpdateSubmission(submission *models.TeamSubmission) error
+	GetTaskHints(taskID uuid.UUID) ([]models.TaskHint, error)
+	GetCompanyTasks(contestID, companyID uuid.UUID) ([]models.Task, error)
+	AddMember(member *models.TeamMember) error
+	GetMembers(teamID uuid.UUID) ([]models.TeamMember, error)
+	FindMemberByTelegramID(telegramID int64) (*models.TeamMember, error)
+	CreateInvite(invite *models.TeamInvite) error
+	FindInviteByCode(code string) (*models.TeamInvite, error)
+	UseInvite(invite *models.TeamInvite, member *models.TeamMember) error
+	GetAnswersByTeam(teamID uuid.UUID) ([]models.TeamAnswer, error)
+	GetAnswersByTask(taskID uuid.UUID) ([]models.TeamAnswer, error)
+	GetSessionsByTeam(teamID uuid.UUID) ([]models.TeamTaskSession, error)
+	GetSessionsByTask(taskID uuid.UUID) ([]models.TeamTaskSession, error)
+	GetSessionsByTasks(taskIDs []uuid.UUID) ([]models.TeamTaskSession, error)
+	GetAnswersByTasks(taskIDs []uuid.UUID) ([]models.TeamAnswer, error)
+	GetTasksByIDs(ids []uuid.UUID) ([]models.Task, error)
+	GetTeamsByIDs(ids []uuid.UUID) ([]models.Team, error)
+	List(filter TeamFilter) ([]models.Team, error)
+	RemoveMembers(teamID uuid.UUID) error
+	CreateAdjustment(adjustment *models.TeamAdjustment) error
+	GetAdjustmentByID(id uuid.UUID) (*models.TeamAdjustment, error)
+	GetAdjustmentsByTeam(teamID uuid.UUID) ([]models.TeamAdjustment, error)
+	GetActiveAdjustments(teamIDs []uuid.UUID) ([]models.TeamAdjustment, error)
+	UpdateAdjustment(adjustment *models.TeamAdjustment) error
+	CreateApprovalRequest(request *models.ApprovalRequest) error
+	GetApprovalRequestByID(id uuid.UUID) (*models.ApprovalRequest, error)
+	GetActiveApprovalRequest(teamID uuid.UUID) (*models.ApprovalRequest, error)
+	GetApprovalRequestsByTeam(teamID uuid.UUID) ([]models.ApprovalRequest, error)
+	GetApprovalRequestsByCompany(companyID uuid.UUID, status string) ([]models.ApprovalRequest, error)
+	UpdateApprovalRequest(request *models.ApprovalRequest) error
+	ApproveRequest(request *models.ApprovalRequest, capacity int) error
+	ReleaseTeam(teamID uuid.UUID) error
+	ReassignTeam(team *models.Team, request *models.ApprovalRequest, capacity int) error
+	CountQueueAhead(request *models.ApprovalRequest) (int64, error)
+	CountCompanyTeams(companyID uuid.UUID) (int64, error)
+	GetContestCompanies(contestID uuid.UUID) ([]models.Company, error)
+	CreateFlag(flag *models.AnswerFlag) error
+	GetFlags(filter FlagFilter) ([]models.AnswerFlag, error)
+	FindSameWrongAnswer(taskID, teamID uuid.UUID, answer string) (*models.TeamAnswer, error)
+}
+
+// FlagFilter условия отбора подозрительных ответов, пустые поля не учитываются
+type FlagFilter struct {
+	ContestID *uuid.UUID
+	TeamID    *uuid.UUID
+	Kind      string
 }
 
+// ErrCapacityReached компания уже принимает максимальное число команд
+var ErrCapacityReached = errors.New("company capacity reached")
+
+// ErrInviteUsed приглашение уже использовано другим участником
+var ErrInviteUsed = errors.New("invite already used")
+
 // GormTeamRepository имплементация TeamRepository с использованием GORM
 type GormTeamRepository struct {
 	db *gorm.DB
@@ -61,10 +127,13 @@ func (r *GormTeamRepository) FindByEmail(email string) (*models.Team, error) {
 	return &team, nil
 }
 
-// FindByTelegramID находит команду по Telegram ID
+// FindByTelegramID находит команду по Telegram ID любого из ее участников
 func (r *GormTeamRepository) FindByTelegramID(telegramID int64) (*models.Team, error) {
 	var team models.Team
-	if err := r.db.Where("telegram_id = ?", telegramID).First(&team).Error; err != nil {
+	err := r.db.Where("telegram_id = ? OR id IN (?)", telegramID,
+		r.db.Model(&models.TeamMember{}).Select("team_id").Where("telegram_id = ?", telegramID)).
+		First(&team).Error
+	if err != nil {
 		return nil, err
 	}
 	return &team, nil
@@ -75,9 +144,40 @@ func (r *GormTeamRepository) Update(team *models.Team) error {
 	return r.db.Save(team).Error
 }
 
-// Delete удаляет команду из базы данных
+// CreateWithEmail создает команду и ставит письмо ей в очередь в одной транзакции
+func (r *GormTeamRepository) CreateWithEmail(team *models.Team, email *models.OutboxEmail) error {
+	return withEmail(r.db, email, func(tx *gorm.DB) error {
+		return tx.Create(team).Error
+	})
+}
+
+// UpdateWithEmail сохраняет команду и ставит письмо ей в очередь в одной транзакции
+func (r *GormTeamRepository) UpdateWithEmail(team *models.Team, email *models.OutboxEmail) error {
+	return withEmail(r.db, email, func(tx *gorm.DB) error {
+		return tx.Save(team).Error
+	})
+}
+
+// Delete удаляет команду вместе с участниками, приглашениями и результатами
 func (r *GormTeamRepository) Delete(id uuid.UUID) error {
-	return r.db.Delete(&models.Team{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		related := []interface{}{
+			&models.TeamMember{},
+			&models.TeamInvite{},
+			&models.TeamAdjustment{},
+			&models.ApprovalRequest{},
+			&models.AnswerFlag{},
+			&models.TeamSubmission{},
+			&models.TeamAnswer{},
+			&models.TeamTaskSession{},
+		}
+		for _, model := range related {
+			if err := tx.Where("team_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&models.Team{}, "id = ?", id).Error
+	})
 }
 
 // SaveAnswer сохраняет ответ команды на задачу
@@ -129,18 +229,6 @@ func (r *GormTeamRepository) GetTaskForTeam(teamID uuid.UUID, contestID uuid.UUI
 	return &task, err
 }
 
-func (r *GormTeamRepository) GetUnassignedTeams() ([]models.Team, error) {
-	var teams []models.Team
-	err := r.db.Where("company_id IS NULL").Find(&teams).Error
-	return teams, err
-}
-
-func (r *GormTeamRepository) ApproveTeam(teamID, companyID uuid.UUID) error {
-	return r.db.Model(&models.Team{}).
-		Where("id = ?", teamID).
-		Update("company_id", companyID).Error
-}
-
 func (r *GormTeamRepository) CreateTaskSession(session *models.TeamTaskSession) error {
 	return r.db.Create(session).Error
 }
@@ -167,3 +255,439 @@ func (r *GormTeamRepository) GetUsedTaskIDs(teamID uuid.UUID) ([]uuid.UUID, erro
 		Pluck("task_id", &ids).Error
 	return ids, err
 }
+
+func (r *GormTeamRepository) GetTaskSessionByID(id uuid.UUID) (*models.TeamTaskSession, error) {
+	var session models.TeamTaskSession
+	if err := r.db.Where("id = ?", id).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// CreateSubmission сохраняет присланный командой файл, ожидающий проверки
+func (r *GormTeamRepository) CreateSubmission(submission *models.TeamSubmission) error {
+	return r.db.Create(submission).Error
+}
+
+// GetSubmissionByID находит присланный файл по ID
+func (r *GormTeamRepository) GetSubmissionByID(id uuid.UUID) (*models.TeamSubmission, error) {
+	var submission models.TeamSubmission
+	if err := r.db.Where("id = ?", id).First(&submission).Error; err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
+
+// GetPendingSubmissionBySession находит непроверенный файл в рамках сессии задачи
+func (r *GormTeamRepository) GetPendingSubmissionBySession(sessionID uuid.UUID) (*models.TeamSubmission, error) {
+	var submission models.TeamSubmission
+	err := r.db.Where("session_id = ? AND status = ?", sessionID, models.SubmissionPending).
+		First(&submission).Error
+	if err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
+
+// GetSubmissionsByCompany возвращает присланные файлы по задачам компании,
+// при пустом status возвращаются файлы в любом статусе
+func (r *GormTeamRepository) GetSubmissionsByCompany(companyID uuid.UUID, status string) ([]models.TeamSubmission, error) {
+	var submissions []models.TeamSubmission
+	query := r.db.Where("company_id = ?", companyID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("created_at asc").Find(&submissions).Error
+	return submissions, err
+}
+
+func (r *GormTeamRepository) UpdateSubmission(submission *models.TeamSubmission) error {
+	return r.db.Save(submission).Error
+}
+
+// GetTaskHints возвращает подсказки задачи в порядке открытия
+func (r *GormTeamRepository) GetTaskHints(taskID uuid.UUID) ([]models.TaskHint, error) {
+	var hints []models.TaskHint
+	err := r.db.Where("task_id = ?", taskID).Order("position asc").Find(&hints).Error
+	return hints, err
+}
+
+// GetCompanyTasks возвращает все задачи компании в контесте, порядок выдачи задает сервис
+func (r *GormTeamRepository) GetCompanyTasks(contestID, companyID uuid.UUID) ([]models.Task, error) {
+	var tasks []models.Task
+	err := r.db.Where("contest_id = ? AND company_id = ?", contestID, companyID).
+		Order("sort_order asc, created_at asc, id asc").
+		Find(&tasks).Error
+	return tasks, err
+}
+
+// AddMember добавляет Telegram-аккаунт в команду
+func (r *GormTeamRepository) AddMember(member *models.TeamMember) error {
+	return r.db.Create(member).Error
+}
+
+// GetMembers возвращает участников команды, капитан первым
+func (r *GormTeamRepository) GetMembers(teamID uuid.UUID) ([]models.TeamMember, error) {
+	var members []models.TeamMember
+	err := r.db.Where("team_id = ?", teamID).
+		Order("CASE WHEN role = 'captain' THEN 0 ELSE 1 END, created_at asc").
+		Find(&members).Error
+	return members, err
+}
+
+// FindMemberByTelegramID находит участника команды по Telegram ID
+func (r *GormTeamRepository) FindMemberByTelegramID(telegramID int64) (*models.TeamMember, error) {
+	var member models.TeamMember
+	if err := r.db.Where("telegram_id = ?", telegramID).First(&member).Error; err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *GormTeamRepository) CreateInvite(invite *models.TeamInvite) error {
+	return r.db.Create(invite).Error
+}
+
+// FindInviteByCode находит приглашение по коду
+func (r *GormTeamRepository) FindInviteByCode(code string) (*models.TeamInvite, error) {
+	var invite models.TeamInvite
+	if err := r.db.Where("code = ?", code).First(&invite).Error; err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// UseInvite помечает приглашение использованным и добавляет участника в одной транзакции.
+// Приглашение списывается условным UPDATE, поэтому одним кодом может воспользоваться только один аккаунт.
+func (r *GormTeamRepository) UseInvite(invite *models.TeamInvite, member *models.TeamMember) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.TeamInvite{}).
+			Where("id = ? AND used_at IS NULL", invite.ID).
+			Updates(map[string]interface{}{"used_at": now, "used_by": member.TelegramID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInviteUsed
+		}
+		invite.UsedAt = &now
+		invite.UsedBy = member.TelegramID
+
+		return tx.Create(member).Error
+	})
+}
+
+// GetAnswersByTeam возвращает все ответы команды в порядке отправки
+func (r *GormTeamRepository) GetAnswersByTeam(teamID uuid.UUID) ([]models.TeamAnswer, error) {
+	var answers []models.TeamAnswer
+	err := r.db.Where("team_id = ?", teamID).Order("created_at asc").Find(&answers).Error
+	return answers, err
+}
+
+// GetAnswersByTask возвращает ответы всех команд на задачу в порядке отправки
+func (r *GormTeamRepository) GetAnswersByTask(taskID uuid.UUID) ([]models.TeamAnswer, error) {
+	var answers []models.TeamAnswer
+	err := r.db.Where("task_id = ?", taskID).Order("created_at asc").Find(&answers).Error
+	return answers, err
+}
+
+// GetSessionsByTeam возвращает сессии задач команды в порядке выдачи
+func (r *GormTeamRepository) GetSessionsByTeam(teamID uuid.UUID) ([]models.TeamTaskSession, error) {
+	var sessions []models.TeamTaskSession
+	err := r.db.Where("team_id = ?", teamID).Order("start_time asc").Find(&sessions).Error
+	return sessions, err
+}
+
+// GetSessionsByTask возвращает сессии всех команд по задаче
+func (r *GormTeamRepository) GetSessionsByTask(taskID uuid.UUID) ([]models.TeamTaskSession, error) {
+	var sessions []models.TeamTaskSession
+	err := r.db.Where("task_id = ?", taskID).Order("start_time asc").Find(&sessions).Error
+	return sessions, err
+}
+
+// GetSessionsByTasks возвращает сессии по набору задач, например по всем задачам компании
+func (r *GormTeamRepository) GetSessionsByTasks(taskIDs []uuid.UUID) ([]models.TeamTaskSession, error) {
+	var sessions []models.TeamTaskSession
+	if len(taskIDs) == 0 {
+		return sessions, nil
+	}
+	err := r.db.Where("task_id IN ?", taskIDs).Order("start_time asc").Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *GormTeamRepository) GetAnswersByTasks(taskIDs []uuid.UUID) ([]models.TeamAnswer, error) {
+	var answers []models.TeamAnswer
+	if len(taskIDs) == 0 {
+		return answers, nil
+	}
+	err := r.db.Where("task_id IN ?", taskIDs).Order("created_at asc").Find(&answers).Error
+	return answers, err
+}
+
+func (r *GormTeamRepository) GetTasksByIDs(ids []uuid.UUID) ([]models.Task, error) {
+	var tasks []models.Task
+	if len(ids) == 0 {
+		return tasks, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&tasks).Error
+	return tasks, err
+}
+
+func (r *GormTeamRepository) GetTeamsByIDs(ids []uuid.UUID) ([]models.Team, error) {
+	var teams []models.Team
+	if len(ids) == 0 {
+		return teams, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&teams).Error
+	return teams, err
+}
+
+// List возвращает команды, подходящие под фильтр
+func (r *GormTeamRepository) List(filter TeamFilter) ([]models.Team, error) {
+	var teams []models.Team
+	query := r.db.Model(&models.Team{})
+	if filter.ContestID != nil {
+		query = query.Where("contest_id = ?", *filter.ContestID)
+	}
+	if filter.CompanyID != nil {
+		query = query.Where("company_id = ?", *filter.CompanyID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	err := query.Order("created_at asc").Find(&teams).Error
+	return teams, err
+}
+
+// RemoveMembers отвязывает от команды все Telegram-аккаунты
+func (r *GormTeamRepository) RemoveMembers(teamID uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("team_id = ?", teamID).Delete(&models.TeamMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Team{}).Where("id = ?", teamID).Update("telegram_id", nil).Error
+	})
+}
+
+func (r *GormTeamRepository) CreateAdjustment(adjustment *models.TeamAdjustment) error {
+	return r.db.Create(adjustment).Error
+}
+
+func (r *GormTeamRepository) GetAdjustmentByID(id uuid.UUID) (*models.TeamAdjustment, error) {
+	var adjustment models.TeamAdjustment
+	if err := r.db.Where("id = ?", id).First(&adjustment).Error; err != nil {
+		return nil, err
+	}
+	return &adjustment, nil
+}
+
+// GetAdjustmentsByTeam возвращает журнал корректировок команды, включая отозванные
+func (r *GormTeamRepository) GetAdjustmentsByTeam(teamID uuid.UUID) ([]models.TeamAdjustment, error) {
+	var adjustments []models.TeamAdjustment
+	err := r.db.Where("team_id = ?", teamID).Order("created_at asc").Find(&adjustments).Error
+	return adjustments, err
+}
+
+// GetActiveAdjustments возвращает неотозванные корректировки указанных команд
+func (r *GormTeamRepository) GetActiveAdjustments(teamIDs []uuid.UUID) ([]models.TeamAdjustment, error) {
+	var adjustments []models.TeamAdjustment
+	if len(teamIDs) == 0 {
+		return adjustments, nil
+	}
+	err := r.db.Where("team_id IN ? AND revoked_at IS NULL", teamIDs).Find(&adjustments).Error
+	return adjustments, err
+}
+
+func (r *GormTeamRepository) UpdateAdjustment(adjustment *models.TeamAdjustment) error {
+	return r.db.Save(adjustment).Error
+}
+
+func (r *GormTeamRepository) CreateApprovalRequest(request *models.ApprovalRequest) error {
+	return r.db.Create(request).Error
+}
+
+func (r *GormTeamRepository) GetApprovalRequestByID(id uuid.UUID) (*models.ApprovalRequest, error) {
+	var request models.ApprovalRequest
+	if err := r.db.Where("id = ?", id).First(&request).Error; err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// GetActiveApprovalRequest возвращает ожидающую или одобренную заявку команды
+func (r *GormTeamRepository) GetActiveApprovalRequest(teamID uuid.UUID) (*models.ApprovalRequest, error) {
+	var request models.ApprovalRequest
+	err := r.db.Where("team_id = ? AND status IN ?", teamID, []string{models.ApprovalPending, models.ApprovalApproved}).
+		Order("created_at desc").
+		First(&request).Error
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *GormTeamRepository) GetApprovalRequestsByTeam(teamID uuid.UUID) ([]models.ApprovalRequest, error) {
+	var requests []models.ApprovalRequest
+	err := r.db.Where("team_id = ?", teamID).Order("created_at asc").Find(&requests).Error
+	return requests, err
+}
+
+// GetApprovalRequestsByCompany возвращает заявки к компании в порядке очереди, пустой статус - все заявки
+func (r *GormTeamRepository) GetApprovalRequestsByCompany(companyID uuid.UUID, status string) ([]models.ApprovalRequest, error) {
+	var requests []models.ApprovalRequest
+	query := r.db.Where("company_id = ?", companyID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("created_at asc").Find(&requests).Error
+	return requests, err
+}
+
+func (r *GormTeamRepository) UpdateApprovalRequest(request *models.ApprovalRequest) error {
+	return r.db.Save(request).Error
+}
+
+// ApproveRequest одобряет заявку и закрепляет команду за компанией.
+// Строка компании блокируется, чтобы параллельные одобрения не превысили capacity.
+func (r *GormTeamRepository) ApproveRequest(request *models.ApprovalRequest, capacity int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return approveRequest(tx, request, capacity)
+	})
+}
+
+// ReleaseTeam открепляет команду от компании и освобождает место в ней
+func (r *GormTeamRepository) ReleaseTeam(teamID uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return releaseTeam(tx, teamID)
+	})
+}
+
+// ReassignTeam сохраняет команду и переводит ее к другой компании в одной транзакции:
+// место в прежней компании освобождается, а заявка к новой одобряется с учетом вместимости.
+// Если request равен nil, команда только открепляется от компании.
+func (r *GormTeamRepository) ReassignTeam(team *models.Team, request *models.ApprovalRequest, capacity int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(team).Error; err != nil {
+			return err
+		}
+		if err := releaseTeam(tx, team.ID); err != nil {
+			return err
+		}
+		if request == nil {
+			return nil
+		}
+		return approveRequest(tx, request, capacity)
+	})
+}
+
+func approveRequest(tx *gorm.DB, request *models.ApprovalRequest, capacity int) error {
+	var company models.Company
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", request.CompanyID).
+		First(&company).Error; err != nil {
+		return err
+	}
+
+	if capacity > 0 {
+		var occupied int64
+		if err := tx.Model(&models.ApprovalRequest{}).
+			Where("company_id = ? AND status = ?", request.CompanyID, models.ApprovalApproved).
+			Count(&occupied).Error; err != nil {
+			return err
+		}
+		if occupied >= int64(capacity) {
+			return ErrCapacityReached
+		}
+	}
+
+	if err := tx.Save(request).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.Team{}).
+		Where("id = ?", request.TeamID).
+		Updates(map[string]interface{}{
+			"company_id":      request.CompanyID,
+			"current_task_id": nil,
+		}).Error
+}
+
+func releaseTeam(tx *gorm.DB, teamID uuid.UUID) error {
+	if err := tx.Model(&models.ApprovalRequest{}).
+		Where("team_id = ? AND status = ?", teamID, models.ApprovalApproved).
+		Update("status", models.ApprovalReleased).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.Team{}).
+		Where("id = ?", teamID).
+		Updates(map[string]interface{}{
+			"company_id":      nil,
+			"current_task_id": nil,
+		}).Error
+}
+
+// CountQueueAhead возвращает число заявок к той же компании, поданных раньше
+func (r *GormTeamRepository) CountQueueAhead(request *models.ApprovalRequest) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.ApprovalRequest{}).
+		Where("company_id = ? AND status = ? AND created_at < ?", request.CompanyID, models.ApprovalPending, request.CreatedAt).
+		Count(&count).Error
+	return count, err
+}
+
+// CountCompanyTeams возвращает число команд, которые сейчас проходят задачи компании
+func (r *GormTeamRepository) CountCompanyTeams(companyID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.ApprovalRequest{}).
+		Where("company_id = ? AND status = ?", companyID, models.ApprovalApproved).
+		Count(&count).Error
+	return count, err
+}
+
+// GetContestCompanies возвращает компании, у которых есть задачи в контесте
+func (r *GormTeamRepository) GetContestCompanies(contestID uuid.UUID) ([]models.Company, error) {
+	var companies []models.Company
+	err := r.db.Where("id IN (?)", r.db.Model(&models.Task{}).Select("company_id").Where("contest_id = ?", contestID)).
+		Order("name asc").
+		Find(&companies).Error
+	return companies, err
+}
+
+// CreateFlag сохраняет отметку, если у сессии еще нет отметки того же типа.
+// Повтор отсекает уникальный индекс, поэтому параллельные ответы не создадут дубликат
+func (r *GormTeamRepository) CreateFlag(flag *models.AnswerFlag) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "session_id"}, {Name: "kind"}},
+		DoNothing: true,
+	}).Create(flag).Error
+}
+
+func (r *GormTeamRepository) GetFlags(filter FlagFilter) ([]models.AnswerFlag, error) {
+	var flags []models.AnswerFlag
+	query := r.db.Model(&models.AnswerFlag{})
+	if filter.ContestID != nil {
+		query = query.Where("contest_id = ?", *filter.ContestID)
+	}
+	if filter.TeamID != nil {
+		query = query.Where("team_id = ?", *filter.TeamID)
+	}
+	if filter.Kind != "" {
+		query = query.Where("kind = ?", filter.Kind)
+	}
+	err := query.Order("created_at desc").Find(&flags).Error
+	return flags, err
+}
+
+// FindSameWrongAnswer ищет такой же неправильный ответ другой команды на эту задачу без учета регистра и пробелов
+func (r *GormTeamRepository) FindSameWrongAnswer(taskID, teamID uuid.UUID, answer string) (*models.TeamAnswer, error) {
+	var found models.TeamAnswer
+	err := r.db.Where("task_id = ? AND team_id <> ? AND is_correct = ? AND LOWER(TRIM(answer)) = LOWER(TRIM(?))", taskID, teamID, false, answer).
+		Order("created_at asc").
+		First(&found).Error
+	if err != nil {
+		return nil, err
+	}
+	return &found, nil
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository struct {
@@ -45,7 +46,12 @@ func (r *Repository) UpdateCompany(ctx context.Context, company *models.Company)
 }
 
 func (r *Repository) DeleteCompany(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.Company{}, "id = ?", id).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.CompanyUser{}, "company_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Company{}, "id = ?", id).Error
+	})
 }
 
 func (r *Repository) CreateContest(ctx context.Context, contest *models.Contest) error {
@@ -83,15 +89,157 @@ func (r *Repository) GetTaskByID(ctx context.Context, id uuid.UUID) (*models.Tas
 }
 
 func (r *Repository) DeleteTask(ctx context.Context, taskID uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.Task{}, "id = ?", taskID).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("task_id = ?", taskID).Delete(&models.TaskHint{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Task{}, "id = ?", taskID).Error
+	})
 }
 
 func (r *Repository) GetTasksByCompanyID(ctx context.Context, companyID uuid.UUID) ([]models.Task, error) {
 	var tasks []models.Task
-	err := r.db.WithContext(ctx).Where("company_id = ?", companyID).Find(&tasks).Error
+	err := r.db.WithContext(ctx).
+		Preload("Hints", func(db *gorm.DB) *gorm.DB { return db.Order("position asc") }).
+		Where("company_id = ?", companyID).
+		Order("sort_order asc, created_at asc").
+		Find(&tasks).Error
 	return tasks, err
 }
 
+// UpdateTask saves the task itself; hints are managed by ReplaceTaskHints
 func (r *Repository) UpdateTask(ctx context.Context, task *models.Task) error {
-	return r.db.WithContext(ctx).Save(task).Error
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(task).Error
+}
+
+// ReplaceTaskHints replaces all hints of the task with the given texts in order
+func (r *Repository) ReplaceTaskHints(ctx context.Context, taskID uuid.UUID, texts []string) ([]models.TaskHint, error) {
+	hints := make([]models.TaskHint, 0, len(texts))
+	for i, text := range texts {
+		hints = append(hints, models.TaskHint{TaskID: taskID, Position: i + 1, Text: text})
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("task_id = ?", taskID).Delete(&models.TaskHint{}).Error; err != nil {
+			return err
+		}
+		if len(hints) == 0 {
+			return nil
+		}
+		return tx.Create(&hints).Error
+	})
+	return hints, err
+}
+
+func (r *Repository) CreateCompanyUser(ctx context.Context, user *models.CompanyUser) error {
+	return r.db.WithContext(ctx).Create(user).Error
+}
+
+func (r *Repository) GetCompanyUserByID(ctx context.Context, id uuid.UUID) (*models.CompanyUser, error) {
+	var user models.CompanyUser
+	err := r.db.WithContext(ctx).First(&user, "id = ?", id).Error
+	return &user, err
+}
+
+func (r *Repository) GetCompanyUserByEmail(ctx context.Context, email string) (*models.CompanyUser, error) {
+	var user models.CompanyUser
+	err := r.db.WithContext(ctx).First(&user, "email = ?", email).Error
+	return &user, err
+}
+
+func (r *Repository) GetCompanyUserByInviteCode(ctx context.Context, codeHash string) (*models.CompanyUser, error) {
+	var user models.CompanyUser
+	err := r.db.WithContext(ctx).First(&user, "invite_code_hash = ?", codeHash).Error
+	return &user, err
+}
+
+func (r *Repository) GetCompanyUsers(ctx context.Context, companyID uuid.UUID) ([]models.CompanyUser, error) {
+	var users []models.CompanyUser
+	err := r.db.WithContext(ctx).Where("company_id = ?", companyID).Order("created_at asc").Find(&users).Error
+	return users, err
+}
+
+func (r *Repository) UpdateCompanyUser(ctx context.Context, user *models.CompanyUser) error {
+	return r.db.WithContext(ctx).Save(user).Error
+}
+
+func (r *Repository) DeleteCompanyUser(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&models.CompanyUser{}, "id = ?", id).Error
+}
+
+func (r *Repository) CreateAdmin(ctx context.Context, admin *models.Admin) error {
+	return r.db.WithContext(ctx).Create(admin).Error
+}
+
+func (r *Repository) GetAdminByID(ctx context.Context, id uuid.UUID) (*models.Admin, error) {
+	var admin models.Admin
+	err := r.db.WithContext(ctx).First(&admin, "id = ?", id).Error
+	return &admin, err
+}
+
+func (r *Repository) GetAdminByUsername(ctx context.Context, username string) (*models.Admin, error) {
+	var admin models.Admin
+	err := r.db.WithContext(ctx).First(&admin, "username = ?", username).Error
+	return &admin, err
+}
+
+func (r *Repository) GetAllAdmins(ctx context.Context) ([]models.Admin, error) {
+	var admins []models.Admin
+	err := r.db.WithContext(ctx).Order("created_at asc").Find(&admins).Error
+	return admins, err
+}
+
+func (r *Repository) CountAdmins(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Admin{}).Count(&count).Error
+	return count, err
+}
+
+func (r *Repository) UpdateAdmin(ctx context.Context, admin *models.Admin) error {
+	return r.db.WithContext(ctx).Save(admin).Error
+}
+
+// ErrLastAdmin удаление оставило бы систему без администраторов
+var ErrLastAdmin = errors.New("cannot delete the last admin")
+
+// DeleteAdmin удаляет администратора, если он не последний. Строки администраторов
+// блокируются, поэтому два одновременных удаления не оставят систему пустой.
+func (r *Repository) DeleteAdmin(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []uuid.UUID
+		if err := tx.Model(&models.Admin{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) <= 1 {
+			return ErrLastAdmin
+		}
+		return tx.Delete(&models.Admin{}, "id = ?", id).Error
+	})
+}
+
+func (r *Repository) CreateAdminLoginAttempt(ctx context.Context, attempt *models.AdminLoginAttempt) error {
+	return r.db.WithContext(ctx).Create(attempt).Error
+}
+
+// GetAdminLoginAttempts возвращает последние попытки входа, новые первыми
+func (r *Repository) GetAdminLoginAttempts(ctx context.Context, limit int) ([]models.AdminLoginAttempt, error) {
+	var attempts []models.AdminLoginAttempt
+	err := r.db.WithContext(ctx).Order("created_at desc").Limit(limit).Find(&attempts).Error
+	return attempts, err
+}
+
+// GetCompaniesWithoutPassword возвращает компании, созданные до хранения хеша пароля
+func (r *Repository) GetCompaniesWithoutPassword(ctx context.Context) ([]models.Company, error) {
+	var companies []models.Company
+	err := r.db.WithContext(ctx).Where("password_hash = '' OR password_hash IS NULL").Find(&companies).Error
+	return companies, err
+}
+
+// GetCompanyBySetupToken находит компанию по хешу одноразовой ссылки установки пароля
+func (r *Repository) GetCompanyBySetupToken(ctx context.Context, tokenHash string) (*models.Company, error) {
+	var company models.Company
+	err := r.db.WithContext(ctx).First(&company, "setup_token_hash = ? AND setup_token_hash <> ''", tokenHash).Error
+	return &company, err
 }
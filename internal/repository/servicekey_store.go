@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"Cyber-chase/internal/models"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FindServiceKeyByHash находит ключ внутреннего API по хешу, реализует auth.ServiceKeyStore
+func (r *Repository) FindServiceKeyByHash(ctx context.Context, hash string) (*models.ServiceKey, error) {
+	var key models.ServiceKey
+	err := r.db.WithContext(ctx).First(&key, "key_hash = ?", hash).Error
+	return &key, err
+}
+
+// TouchServiceKey запоминает время последнего использования ключа
+func (r *Repository) TouchServiceKey(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.ServiceKey{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+}
+
+func (r *Repository) CreateServiceKey(ctx context.Context, key *models.ServiceKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *Repository) GetServiceKeys(ctx context.Context) ([]models.ServiceKey, error) {
+	var keys []models.ServiceKey
+	err := r.db.WithContext(ctx).Order("created_at desc").Find(&keys).Error
+	return keys, err
+}
+
+func (r *Repository) GetServiceKeyByID(ctx context.Context, id uuid.UUID) (*models.ServiceKey, error) {
+	var key models.ServiceKey
+	err := r.db.WithContext(ctx).First(&key, "id = ?", id).Error
+	return &key, err
+}
+
+func (r *Repository) UpdateServiceKey(ctx context.Context, key *models.ServiceKey) error {
+	return r.db.WithContext(ctx).Save(key).Error
+}
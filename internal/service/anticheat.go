@@ -0,0 +1,155 @@
+package service
+
+import (
+	"Cyber-chase/internal/models"
+	"Cyber-chase/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AntiCheatConfig пороги эвристик для подозрительных ответов
+type AntiCheatConfig struct {
+	// Правильный ответ быстрее этого времени после выдачи задачи считается подозрительным
+	FastCorrect time.Duration
+}
+
+// LoadAntiCheatConfig читает пороги из переменных окружения
+func LoadAntiCheatConfig() AntiCheatConfig {
+	return AntiCheatConfig{
+		FastCorrect: time.Duration(envInt("ANTICHEAT_FAST_CORRECT_SECONDS", 15)) * time.Second,
+	}
+}
+
+// FlaggedSession сессия задачи с подозрительными ответами для отчета судьям
+type FlaggedSession struct {
+	TaskResult
+	Flags []FlagInfo `json:"flags"`
+}
+
+// FlagInfo одна сработавшая эвристика
+type FlagInfo struct {
+	ID        uuid.UUID `json:"id"`
+	Kind      string    `json:"kind"`
+	Details   string    `json:"details"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// checkAnswer проверяет ответ эвристиками и сохраняет найденные отметки.
+// Ошибки только логируются, чтобы не мешать командам играть.
+func (s *TeamServiceImpl) checkAnswer(team *models.Team, task *models.Task, session *models.TeamTaskSession, answer string, isCorrect bool) {
+	elapsed := time.Since(session.StartTime)
+
+	if isCorrect && elapsed < s.antiCheat.FastCorrect {
+		what := "правильный ответ"
+		if task.AnswerType == models.AnswerTypePhoto {
+			what = "файл на проверку"
+		}
+		s.flag(team, session, models.FlagFastCorrect, fmt.Sprintf(
+			"%s через %s после выдачи задачи", what, elapsed.Round(time.Second),
+		))
+	}
+
+	if !isCorrect && strings.TrimSpace(answer) != "" {
+		if other, err := s.repo.FindSameWrongAnswer(task.ID, team.ID, answer); err == nil {
+			otherName := other.TeamID.String()
+			if otherTeam, err := s.repo.FindByID(other.TeamID); err == nil {
+				otherName = otherTeam.Name
+			}
+			s.flag(team, session, models.FlagSharedWrongAnswer, fmt.Sprintf(
+				"неправильный ответ %q уже присылала команда %s в %s", answer, otherName, other.CreatedAt.Format("15:04:05"),
+			))
+		}
+	}
+
+	if details := s.checkInProblem(team, task); details != "" {
+		s.flag(team, session, models.FlagNoCheckIn, details)
+	}
+}
+
+// checkInProblem сверяет компанию задачи с одобренной заявкой команды. Задачи выдаются
+// только после одобрения, поэтому расхождение значит, что ответ пришел после освобождения
+// или перевода команды либо задача получена в обход заявки. Пустая строка - все в порядке.
+func (s *TeamServiceImpl) checkInProblem(team *models.Team, task *models.Task) string {
+	request, err := s.repo.GetActiveApprovalRequest(team.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "у команды нет одобренной заявки в компанию этой задачи"
+	}
+	if err != nil {
+		log.Printf("anti-cheat: approval lookup failed for team %s: %v", team.ID, err)
+		return ""
+	}
+
+	if request.CompanyID != task.CompanyID {
+		return fmt.Sprintf("команда сейчас записана в другую компанию (%s)", request.CompanyID)
+	}
+	if request.Status != models.ApprovalApproved {
+		return "заявка команды в компанию этой задачи еще не одобрена"
+	}
+	return ""
+}
+
+func (s *TeamServiceImpl) flag(team *models.Team, session *models.TeamTaskSession, kind, details string) {
+	flag := &models.AnswerFlag{
+		SessionID: session.ID,
+		TeamID:    team.ID,
+		TaskID:    session.TaskID,
+		ContestID: team.ContestID,
+		Kind:      kind,
+		Details:   details,
+	}
+	if err := s.repo.CreateFlag(flag); err != nil {
+		log.Printf("anti-cheat: failed to save %s flag for team %s: %v", kind, team.ID, err)
+	}
+}
+
+// GetFlaggedSessions возвращает сессии с подозрительными ответами, сгруппированные по сессии
+func (s *TeamServiceImpl) GetFlaggedSessions(filter repository.FlagFilter) ([]FlaggedSession, error) {
+	flags, err := s.repo.GetFlags(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var order []uuid.UUID
+	bySession := make(map[uuid.UUID]*FlaggedSession)
+	for _, flag := range flags {
+		entry, ok := bySession[flag.SessionID]
+		if !ok {
+			entry = &FlaggedSession{
+				TaskResult: TaskResult{TaskID: flag.TaskID, TeamID: flag.TeamID, Answers: []AnswerResult{}},
+				Flags:      []FlagInfo{},
+			}
+			bySession[flag.SessionID] = entry
+			order = append(order, flag.SessionID)
+		}
+		entry.Flags = append(entry.Flags, FlagInfo{
+			ID:        flag.ID,
+			Kind:      flag.Kind,
+			Details:   flag.Details,
+			CreatedAt: flag.CreatedAt,
+		})
+	}
+
+	result := make([]FlaggedSession, 0, len(order))
+	for _, id := range order {
+		entry := bySession[id]
+		if session, err := s.repo.GetTaskSessionByID(id); err == nil {
+			answers, _ := s.repo.GetAnswersByTask(session.TaskID)
+			entry.TaskResult = newTaskResult(*session, answers)
+		}
+		if team, err := s.repo.FindByID(entry.TeamID); err == nil {
+			entry.TeamName = team.Name
+		}
+		if task, err := s.coreRepo.GetTaskByID(context.TODO(), entry.TaskID); err == nil {
+			entry.Question = task.Question
+		}
+		result = append(result, *entry)
+	}
+	return result, nil
+}
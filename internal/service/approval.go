@@ -0,0 +1,258 @@
+package service
+
+import (
+	"Cyber-chase/internal/models"
+	"Cyber-chase/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrApprovalNotFound у команды нет ожидающей заявки к этой компании
+var ErrApprovalNotFound = errors.New("approval request not found")
+
+// ErrCapacityReached компания уже принимает максимальное число команд
+var ErrCapacityReached = repository.ErrCapacityReached
+
+// ApprovalRequestInfo заявка команды в очереди компании
+type ApprovalRequestInfo struct {
+	ID        uuid.UUID  `json:"id"`
+	TeamID    uuid.UUID  `json:"team_id"`
+	TeamName  string     `json:"team_name"`
+	Status    string     `json:"status"`
+	Reason    string     `json:"reason,omitempty"`
+	Position  int        `json:"position,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+}
+
+// GetAvailableCompanies возвращает компании контеста, задачи которых команда еще не прошла
+func (s *TeamServiceImpl) GetAvailableCompanies(teamID uuid.UUID) ([]models.Company, error) {
+	team, err := s.repo.FindByID(teamID)
+	if err != nil {
+		return nil, errors.New("team not found")
+	}
+
+	if team.ContestID == nil {
+		return nil, errors.New("команда не участвует в контесте")
+	}
+
+	companies, err := s.repo.GetContestCompanies(*team.ContestID)
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := s.repo.GetApprovalRequestsByTeam(team.ID)
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[uuid.UUID]bool)
+	for _, request := range requests {
+		if request.Status == models.ApprovalReleased {
+			done[request.CompanyID] = true
+		}
+	}
+
+	available := make([]models.Company, 0, len(companies))
+	for _, company := range companies {
+		if !done[company.ID] {
+			available = append(available, company)
+		}
+	}
+	return available, nil
+}
+
+// RequestCompany ставит команду в очередь к выбранной компании и возвращает позицию в очереди.
+// Предыдущая ожидающая заявка к другой компании отменяется.
+func (s *TeamServiceImpl) RequestCompany(teamID, companyID uuid.UUID) (*models.ApprovalRequest, int, error) {
+	team, err := s.repo.FindByID(teamID)
+	if err != nil {
+		return nil, 0, errors.New("team not found")
+	}
+
+	if team.Status == models.TeamStatusDisqualified {
+		return nil, 0, ErrTeamDisqualified
+	}
+
+	if team.CompanyID != nil {
+		return nil, 0, errors.New("команда уже проходит задачи компании")
+	}
+
+	companies, err := s.GetAvailableCompanies(team.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+	found := false
+	for _, company := range companies {
+		if company.ID == companyID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, 0, errors.New("компания недоступна для команды")
+	}
+
+	if current, err := s.repo.GetActiveApprovalRequest(team.ID); err == nil {
+		if current.CompanyID == companyID {
+			position, err := s.queuePosition(current)
+			return current, position, err
+		}
+
+		now := time.Now()
+		current.Status = models.ApprovalCancelled
+		current.DecidedAt = &now
+		if err := s.repo.UpdateApprovalRequest(current); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	request := &models.ApprovalRequest{
+		TeamID:    team.ID,
+		CompanyID: companyID,
+		ContestID: *team.ContestID,
+		Status:    models.ApprovalPending,
+	}
+	if err := s.repo.CreateApprovalRequest(request); err != nil {
+		return nil, 0, err
+	}
+	s.record(team, "approval.request", "approval_request", request.ID, nil, request)
+
+	position, err := s.queuePosition(request)
+	return request, position, err
+}
+
+// GetApprovalStatus возвращает текущую заявку команды и ее позицию в очереди,
+// для одобренной заявки позиция равна нулю
+func (s *TeamServiceImpl) GetApprovalStatus(teamID uuid.UUID) (*models.ApprovalRequest, int, error) {
+	requests, err := s.repo.GetApprovalRequestsByTeam(teamID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(requests) == 0 {
+		return nil, 0, ErrApprovalNotFound
+	}
+
+	request := requests[len(requests)-1]
+	if request.Status != models.ApprovalPending {
+		return &request, 0, nil
+	}
+
+	position, err := s.queuePosition(&request)
+	return &request, position, err
+}
+
+// GetApprovalRequests возвращает заявки команд к компании, пустой статус - все заявки
+func (s *TeamServiceImpl) GetApprovalRequests(companyID uuid.UUID, status string) ([]ApprovalRequestInfo, error) {
+	requests, err := s.repo.GetApprovalRequestsByCompany(companyID, status)
+	if err != nil {
+		return nil, err
+	}
+
+	teamIDs := make([]uuid.UUID, 0, len(requests))
+	for _, request := range requests {
+		teamIDs = append(teamIDs, request.TeamID)
+	}
+	teams, err := s.repo.GetTeamsByIDs(teamIDs)
+	if err != nil {
+		return nil, err
+	}
+	teamNames := make(map[uuid.UUID]string, len(teams))
+	for _, team := range teams {
+		teamNames[team.ID] = team.Name
+	}
+
+	result := make([]ApprovalRequestInfo, 0, len(requests))
+	position := 0
+	for _, request := range requests {
+		info := ApprovalRequestInfo{
+			ID:        request.ID,
+			TeamID:    request.TeamID,
+			TeamName:  teamNames[request.TeamID],
+			Status:    request.Status,
+			Reason:    request.Reason,
+			CreatedAt: request.CreatedAt,
+			DecidedAt: request.DecidedAt,
+		}
+		// Заявки отсортированы по времени подачи, поэтому позиция считается по порядку
+		if request.Status == models.ApprovalPending {
+			position++
+			info.Position = position
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+// ApproveTeam одобряет заявку команды к компании с учетом ее вместимости
+func (s *TeamServiceImpl) ApproveTeam(teamID, companyID uuid.UUID) error {
+	request, err := s.pendingRequest(teamID, companyID)
+	if err != nil {
+		return err
+	}
+
+	company, err := s.coreRepo.GetCompanyByID(context.Background(), companyID)
+	if err != nil {
+		return errors.New("company not found")
+	}
+
+	now := time.Now()
+	request.Status = models.ApprovalApproved
+	request.DecidedAt = &now
+	if err := s.repo.ApproveRequest(request, company.Capacity); err != nil {
+		return err
+	}
+
+	if team, err := s.repo.FindByID(teamID); err == nil {
+		s.notifyTeam(team, fmt.Sprintf("🎉 Компания %s одобрила вашу команду! Можно получать задания.", company.Name))
+	}
+	return nil
+}
+
+// RejectTeam отклоняет заявку команды и сообщает ей причину
+func (s *TeamServiceImpl) RejectTeam(teamID, companyID uuid.UUID, reason string) error {
+	request, err := s.pendingRequest(teamID, companyID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	request.Status = models.ApprovalRejected
+	request.Reason = reason
+	request.DecidedAt = &now
+	if err := s.repo.UpdateApprovalRequest(request); err != nil {
+		return err
+	}
+
+	if team, err := s.repo.FindByID(teamID); err == nil {
+		text := "🚫 Компания отклонила заявку вашей команды."
+		if reason != "" {
+			text += "\nПричина: " + reason
+		}
+		s.notifyTeam(team, text+"\nВыберите другую компанию.")
+	}
+	return nil
+}
+
+// pendingRequest находит ожидающую заявку команды именно к этой компании
+func (s *TeamServiceImpl) pendingRequest(teamID, companyID uuid.UUID) (*models.ApprovalRequest, error) {
+	request, err := s.repo.GetActiveApprovalRequest(teamID)
+	if err != nil || request.CompanyID != companyID || request.Status != models.ApprovalPending {
+		return nil, ErrApprovalNotFound
+	}
+	return request, nil
+}
+
+func (s *TeamServiceImpl) queuePosition(request *models.ApprovalRequest) (int, error) {
+	if request.Status != models.ApprovalPending {
+		return 0, nil
+	}
+	ahead, err := s.repo.CountQueueAhead(request)
+	if err != nil {
+		return 0, err
+	}
+	return int(ahead) + 1, nil
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"Cyber-chase/internal/models"
+	"Cyber-chase/internal/repository"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DashboardTeam команда, которая сейчас проходит задачи компании
+type DashboardTeam struct {
+	TeamID         uuid.UUID  `json:"team_id"`
+	TeamName       string     `json:"team_name"`
+	ActiveTaskID   *uuid.UUID `json:"active_task_id"`
+	ActiveQuestion string     `json:"active_question,omitempty"`
+	StartedAt      *time.Time `json:"started_at,omitempty"`
+	Elapsed        string     `json:"elapsed"`
+	Attempts       int        `json:"attempts"`
+	HintsUsed      int        `json:"hints_used"`
+	CompletedTasks int        `json:"completed_tasks"`
+	TotalTasks     int        `json:"total_tasks"`
+}
+
+// DashboardTaskStats сводная статистика по задаче компании
+type DashboardTaskStats struct {
+	TaskID    uuid.UUID `json:"task_id"`
+	Question  string    `json:"question"`
+	Started   int       `json:"started"`
+	Finished  int       `json:"finished"`
+	Solved    int       `json:"solved"`
+	Skipped   int       `json:"skipped"`
+	Answers   int       `json:"answers"`
+	SolveRate float64   `json:"solve_rate"`
+	// Среднее время решения среди команд, ответивших правильно
+	AvgSolveTime string `json:"avg_solve_time"`
+}
+
+// CompanyDashboard текущее состояние компании во время контеста
+type CompanyDashboard struct {
+	CompanyID       uuid.UUID            `json:"company_id"`
+	Capacity        int                  `json:"capacity"`
+	PendingRequests int                  `json:"pending_requests"`
+	Teams           []DashboardTeam      `json:"teams"`
+	Tasks           []DashboardTaskStats `json:"tasks"`
+}
+
+// GetCompanyDashboard собирает прогресс команд у компании и статистику по ее задачам
+func (s *TeamServiceImpl) GetCompanyDashboard(companyID uuid.UUID) (*CompanyDashboard, error) {
+	company, err := s.coreRepo.GetCompanyByID(context.TODO(), companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := s.coreRepo.GetTasksByCompanyID(context.TODO(), companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	taskIDs := make([]uuid.UUID, 0, len(tasks))
+	questions := make(map[uuid.UUID]string, len(tasks))
+	// Компания может участвовать в нескольких контестах, прогресс команды считается по ее контесту
+	taskContests := make(map[uuid.UUID]uuid.UUID, len(tasks))
+	contestTasks := make(map[uuid.UUID]int)
+	for _, task := range tasks {
+		taskIDs = append(taskIDs, task.ID)
+		questions[task.ID] = task.Question
+		taskContests[task.ID] = task.ContestID
+		contestTasks[task.ContestID]++
+	}
+
+	sessions, err := s.repo.GetSessionsByTasks(taskIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	answers, err := s.repo.GetAnswersByTasks(taskIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	teams, err := s.repo.List(repository.TeamFilter{CompanyID: &companyID})
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := s.repo.GetApprovalRequestsByCompany(companyID, models.ApprovalPending)
+	if err != nil {
+		return nil, err
+	}
+
+	dashboard := &CompanyDashboard{
+		CompanyID:       company.ID,
+		Capacity:        company.Capacity,
+		PendingRequests: len(pending),
+		Teams:           make([]DashboardTeam, 0, len(teams)),
+		Tasks:           make([]DashboardTaskStats, 0, len(tasks)),
+	}
+
+	for _, team := range teams {
+		entry := DashboardTeam{
+			TeamID:       team.ID,
+			TeamName:     team.Name,
+			ActiveTaskID: team.CurrentTaskID,
+			Elapsed:      models.PGInterval(0).String(),
+		}
+		if team.ContestID != nil {
+			entry.TotalTasks = contestTasks[*team.ContestID]
+		}
+
+		for _, session := range sessions {
+			if session.TeamID != team.ID {
+				continue
+			}
+			if team.ContestID == nil || taskContests[session.TaskID] != *team.ContestID {
+				continue
+			}
+			if session.Finished {
+				entry.CompletedTasks++
+				continue
+			}
+			if team.CurrentTaskID != nil && session.TaskID == *team.CurrentTaskID {
+				startedAt := session.StartTime
+				entry.ActiveQuestion = questions[session.TaskID]
+				entry.StartedAt = &startedAt
+				entry.Elapsed = models.PGInterval(time.Since(session.StartTime)).String()
+				entry.Attempts = session.Attempts
+				entry.HintsUsed = session.HintsUsed
+			}
+		}
+
+		dashboard.Teams = append(dashboard.Teams, entry)
+	}
+
+	for _, task := range tasks {
+		stats := DashboardTaskStats{
+			TaskID:       task.ID,
+			Question:     task.Question,
+			AvgSolveTime: models.PGInterval(0).String(),
+		}
+
+		var solveTime time.Duration
+		for _, session := range sessions {
+			if session.TaskID != task.ID {
+				continue
+			}
+			stats.Started++
+			if !session.Finished {
+				continue
+			}
+			stats.Finished++
+			if session.Skipped {
+				stats.Skipped++
+			}
+			if session.IsCorrect {
+				stats.Solved++
+				solveTime += session.Duration.Duration()
+			}
+		}
+
+		for _, answer := range answers {
+			if answer.TaskID == task.ID {
+				stats.Answers++
+			}
+		}
+
+		if stats.Started > 0 {
+			stats.SolveRate = float64(stats.Solved) / float64(stats.Started)
+		}
+		if stats.Solved > 0 {
+			stats.AvgSolveTime = models.PGInterval(solveTime / time.Duration(stats.Solved)).String()
+		}
+
+		dashboard.Tasks = append(dashboard.Tasks, stats)
+	}
+
+	return dashboard, nil
+}
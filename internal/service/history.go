@@ -0,0 +1,153 @@
+package service
+
+import (
+	"Cyber-chase/internal/models"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AnswerResult один ответ команды на задачу
+type AnswerResult struct {
+	Answer    string    `json:"answer"`
+	IsCorrect bool      `json:"is_correct"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TaskResult итог работы команды над одной задачей
+type TaskResult struct {
+	TaskID     uuid.UUID      `json:"task_id"`
+	Question   string         `json:"question"`
+	TeamID     uuid.UUID      `json:"team_id"`
+	TeamName   string         `json:"team_name"`
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt *time.Time     `json:"finished_at"`
+	Duration   string         `json:"duration"`
+	Attempts   int            `json:"attempts"`
+	HintsUsed  int            `json:"hints_used"`
+	Finished   bool           `json:"finished"`
+	IsCorrect  bool           `json:"is_correct"`
+	Skipped    bool           `json:"skipped"`
+	Points     int            `json:"points"`
+	Answers    []AnswerResult `json:"answers"`
+}
+
+// GetTeamHistory возвращает задачи, которые получала команда, с ответами и итогами
+func (s *TeamServiceImpl) GetTeamHistory(teamID uuid.UUID) ([]TaskResult, error) {
+	team, err := s.repo.FindByID(teamID)
+	if err != nil {
+		return nil, errors.New("team not found")
+	}
+
+	sessions, err := s.repo.GetSessionsByTeam(teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	answers, err := s.repo.GetAnswersByTeam(teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	taskIDs := make([]uuid.UUID, 0, len(sessions))
+	for _, session := range sessions {
+		taskIDs = append(taskIDs, session.TaskID)
+	}
+	tasks, err := s.repo.GetTasksByIDs(taskIDs)
+	if err != nil {
+		return nil, err
+	}
+	questions := make(map[uuid.UUID]string, len(tasks))
+	for _, task := range tasks {
+		questions[task.ID] = task.Question
+	}
+
+	results := make([]TaskResult, 0, len(sessions))
+	for _, session := range sessions {
+		result := newTaskResult(session, answers)
+		result.Question = questions[session.TaskID]
+		result.TeamName = team.Name
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// GetTaskResults возвращает итоги всех команд по задаче компании
+func (s *TeamServiceImpl) GetTaskResults(companyID, taskID uuid.UUID) ([]TaskResult, error) {
+	task, err := s.coreRepo.GetTaskByID(context.TODO(), taskID)
+	if err != nil || task.CompanyID != companyID {
+		return nil, errors.New("task not found")
+	}
+
+	sessions, err := s.repo.GetSessionsByTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	answers, err := s.repo.GetAnswersByTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	teamIDs := make([]uuid.UUID, 0, len(sessions))
+	for _, session := range sessions {
+		teamIDs = append(teamIDs, session.TeamID)
+	}
+	teams, err := s.repo.GetTeamsByIDs(teamIDs)
+	if err != nil {
+		return nil, err
+	}
+	teamNames := make(map[uuid.UUID]string, len(teams))
+	for _, team := range teams {
+		teamNames[team.ID] = team.Name
+	}
+
+	results := make([]TaskResult, 0, len(sessions))
+	for _, session := range sessions {
+		result := newTaskResult(session, answers)
+		result.Question = task.Question
+		result.TeamName = teamNames[session.TeamID]
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// newTaskResult собирает итог по сессии и ответам этой команды на эту задачу
+func newTaskResult(session models.TeamTaskSession, answers []models.TeamAnswer) TaskResult {
+	duration := session.Duration.Duration()
+	if !session.Finished {
+		// Для незавершенной задачи показываем, сколько времени уже прошло
+		duration = time.Since(session.StartTime)
+		if duration > 10*time.Minute {
+			duration = 10 * time.Minute
+		}
+	}
+
+	result := TaskResult{
+		TaskID:     session.TaskID,
+		TeamID:     session.TeamID,
+		StartedAt:  session.StartTime,
+		FinishedAt: session.FinishedAt,
+		Duration:   models.PGInterval(duration).String(),
+		Attempts:   session.Attempts,
+		HintsUsed:  session.HintsUsed,
+		Finished:   session.Finished,
+		IsCorrect:  session.IsCorrect,
+		Skipped:    session.Skipped,
+		Points:     session.Points,
+		Answers:    []AnswerResult{},
+	}
+
+	for _, answer := range answers {
+		if answer.TeamID == session.TeamID && answer.TaskID == session.TaskID {
+			result.Answers = append(result.Answers, AnswerResult{
+				Answer:    answer.Answer,
+				IsCorrect: answer.IsCorrect,
+				CreatedAt: answer.CreatedAt,
+			})
+		}
+	}
+	return result
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"Cyber-chase/internal/models"
+	"Cyber-chase/internal/repository"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LeaderboardEntry место команды в рейтинге контеста с учетом корректировок
+type LeaderboardEntry struct {
+	Rank          int       `json:"rank"`
+	TeamID        uuid.UUID `json:"team_id"`
+	TeamName      string    `json:"team_name"`
+	Points        int       `json:"points"`
+	TotalDuration string    `json:"total_duration"`
+	// Вклад корректировок судей, уже учтенный в Points и TotalDuration
+	AdjustmentPoints   int    `json:"adjustment_points"`
+	AdjustmentDuration string `json:"adjustment_duration"`
+
+	duration time.Duration
+}
+
+// GetLeaderboard возвращает рейтинг команд контеста: больше очков, затем меньше времени.
+// Дисквалифицированные команды в рейтинг не попадают.
+func (s *TeamServiceImpl) GetLeaderboard(contestID uuid.UUID) ([]LeaderboardEntry, error) {
+	teams, err := s.repo.List(repository.TeamFilter{
+		ContestID: &contestID,
+		Status:    models.TeamStatusActive,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	teamIDs := make([]uuid.UUID, 0, len(teams))
+	for _, team := range teams {
+		teamIDs = append(teamIDs, team.ID)
+	}
+
+	adjustments, err := s.repo.GetActiveAdjustments(teamIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	adjustmentPoints := make(map[uuid.UUID]int)
+	adjustmentDuration := make(map[uuid.UUID]time.Duration)
+	for _, adjustment := range adjustments {
+		adjustmentPoints[adjustment.TeamID] += adjustment.DeltaPoints
+		adjustmentDuration[adjustment.TeamID] += adjustment.DeltaDuration.Duration()
+	}
+
+	entries := make([]LeaderboardEntry, 0, len(teams))
+	for _, team := range teams {
+		duration := team.TotalDuration.Duration() + adjustmentDuration[team.ID]
+		entries = append(entries, LeaderboardEntry{
+			TeamID:             team.ID,
+			TeamName:           team.Name,
+			Points:             team.Points + adjustmentPoints[team.ID],
+			TotalDuration:      models.PGInterval(duration).String(),
+			AdjustmentPoints:   adjustmentPoints[team.ID],
+			AdjustmentDuration: models.PGInterval(adjustmentDuration[team.ID]).String(),
+			duration:           duration,
+		})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Points != entries[j].Points {
+			return entries[i].Points > entries[j].Points
+		}
+		return entries[i].duration < entries[j].duration
+	})
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+
+	return entries, nil
+}
+
+// AddAdjustment добавляет в журнал корректировку очков и времени команды
+func (s *TeamServiceImpl) AddAdjustment(teamID uuid.UUID, deltaPoints int, deltaDuration time.Duration, reason, author string) (*models.TeamAdjustment, error) {
+	team, err := s.repo.FindByID(teamID)
+	if err != nil {
+		return nil, errors.New("team not found")
+	}
+
+	if reason == "" {
+		return nil, errors.New("reason is required")
+	}
+
+	adjustment := &models.TeamAdjustment{
+		TeamID:        team.ID,
+		DeltaPoints:   deltaPoints,
+		DeltaDuration: models.PGInterval(deltaDuration),
+		Reason:        reason,
+		Author:        author,
+	}
+	if err := s.repo.CreateAdjustment(adjustment); err != nil {
+		return nil, err
+	}
+
+	return adjustment, nil
+}
+
+// RevokeAdjustment отзывает корректировку, запись остается в журнале
+func (s *TeamServiceImpl) RevokeAdjustment(teamID, adjustmentID uuid.UUID, author string) (*models.TeamAdjustment, error) {
+	adjustment, err := s.repo.GetAdjustmentByID(adjustmentID)
+	if err != nil || adjustment.TeamID != teamID {
+		return nil, errors.New("adjustment not found")
+	}
+
+	if adjustment.RevokedAt != nil {
+		return nil, errors.New("adjustment already revoked")
+	}
+
+	now := time.Now()
+	adjustment.RevokedAt = &now
+	adjustment.RevokedBy = author
+	if err := s.repo.UpdateAdjustment(adjustment); err != nil {
+		return nil, err
+	}
+
+	return adjustment, nil
+}
+
+// GetAdjustments возвращает журнал корректировок команды
+func (s *TeamServiceImpl) GetAdjustments(teamID uuid.UUID) ([]models.TeamAdjustment, error) {
+	return s.repo.GetAdjustmentsByTeam(teamID)
+}
+
+// DisqualifyTeam снимает команду с рейтинга и блокирует ей выдачу задач
+func (s *TeamServiceImpl) DisqualifyTeam(teamID uuid.UUID, reason string) (*models.Team, error) {
+	team, err := s.repo.FindByID(teamID)
+	if err != nil {
+		return nil, errors.New("team not found")
+	}
+
+	team.Status = models.TeamStatusDisqualified
+	team.DisqualifyReason = reason
+	if err := s.repo.Update(team); err != nil {
+		return nil, err
+	}
+
+	text := "⛔ Ваша команда дисквалифицирована."
+	if reason != "" {
+		text += "\nПричина: " + reason
+	}
+	s.notifyTeam(team, text)
+
+	return team, nil
+}
+
+// ReinstateTeam возвращает дисквалифицированную команду в игру
+func (s *TeamServiceImpl) ReinstateTeam(teamID uuid.UUID) (*models.Team, error) {
+	team, err := s.repo.FindByID(teamID)
+	if err != nil {
+		return nil, errors.New("team not found")
+	}
+
+	team.Status = models.TeamStatusActive
+	team.DisqualifyReason = ""
+	if err := s.repo.Update(team); err != nil {
+		return nil, err
+	}
+
+	s.notifyTeam(team, "✅ Дисквалификация снята, команда снова может играть.")
+
+	return team, nil
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"Cyber-chase/internal/models"
+	"Cyber-chase/internal/pkg/mail"
+	"Cyber-chase/internal/repository"
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// ErrContestNotCompleted итоги можно разослать только после завершения контеста
+var ErrContestNotCompleted = errors.New("contest is not completed")
+
+// SendContestReminder ставит в очередь напоминание о начале контеста активным командам,
+// которым оно еще не отправлялось, и возвращает число новых писем
+func (s *TeamServiceImpl) SendContestReminder(contestID uuid.UUID) (int, error) {
+	contest, err := s.coreRepo.GetContestByID(context.TODO(), contestID)
+	if err != nil {
+		return 0, errors.New("contest not found")
+	}
+
+	teams, err := s.repo.List(repository.TeamFilter{
+		ContestID: &contestID,
+		Status:    models.TeamStatusActive,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	emails := make([]*models.OutboxEmail, 0, len(teams))
+	for _, team := range teams {
+		emails = append(emails, mail.ContestReminder(contestID, team.Email, team.Language, team.Name, contest.Name))
+	}
+	return s.coreRepo.EnqueueEmails(context.TODO(), emails)
+}
+
+// SendContestResults ставит в очередь письма с местом, очками и временем командам рейтинга,
+// которым итоги еще не отправлялись
+func (s *TeamServiceImpl) SendContestResults(contestID uuid.UUID) (int, error) {
+	contest, err := s.coreRepo.GetContestByID(context.TODO(), contestID)
+	if err != nil {
+		return 0, errors.New("contest not found")
+	}
+	if contest.Status != "completed" {
+		return 0, ErrContestNotCompleted
+	}
+
+	leaderboard, err := s.GetLeaderboard(contestID)
+	if err != nil {
+		return 0, err
+	}
+
+	teamIDs := make([]uuid.UUID, 0, len(leaderboard))
+	for _, entry := range leaderboard {
+		teamIDs = append(teamIDs, entry.TeamID)
+	}
+	teams, err := s.repo.GetTeamsByIDs(teamIDs)
+	if err != nil {
+		return 0, err
+	}
+	byID := make(map[uuid.UUID]models.Team, len(teams))
+	for _, team := range teams {
+		byID[team.ID] = team
+	}
+
+	emails := make([]*models.OutboxEmail, 0, len(leaderboard))
+	for _, entry := range leaderboard {
+		team, ok := byID[entry.TeamID]
+		if !ok {
+			continue
+		}
+		emails = append(emails, mail.ContestResults(
+			contestID, team.Email, team.Language, team.Name, contest.Name,
+			entry.Rank, len(leaderboard), entry.Points, entry.TotalDuration,
+		))
+	}
+	return s.coreRepo.EnqueueEmails(context.TODO(), emails)
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ScoringConfig настройки начисления очков команде
+type ScoringConfig struct {
+	// HintCost сколько очков снимается за каждую открытую подсказку
+	HintCost int
+	// SkipPenaltyPoints сколько очков снимается за пропуск задачи
+	SkipPenaltyPoints int
+	// SkipPenaltyTime сколько времени добавляется команде за пропуск задачи
+	SkipPenaltyTime time.Duration
+}
+
+// LoadScoringConfig читает настройки начисления очков из переменных окружения
+func LoadScoringConfig() ScoringConfig {
+	return ScoringConfig{
+		HintCost:          envInt("HINT_POINT_COST", 1),
+		SkipPenaltyPoints: envInt("SKIP_PENALTY_POINTS", 0),
+		SkipPenaltyTime:   time.Duration(envInt("SKIP_PENALTY_MINUTES", 5)) * time.Minute,
+	}
+}
+
+// envInt читает целое число из переменной окружения или возвращает значение по умолчанию
+func envInt(key string, def int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid %s=%q, using default %d", key, value, def)
+		return def
+	}
+	return n
+}
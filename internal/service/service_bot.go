@@ -2,6 +2,10 @@ package service
 
 import (
 	"Cyber-chase/internal/models"
+	"Cyber-chase/internal/pkg"
+	"Cyber-chase/internal/pkg/audit"
+	"Cyber-chase/internal/pkg/auth"
+	"Cyber-chase/internal/pkg/mail"
 	"Cyber-chase/internal/repository"
 	"context"
 	"crypto/rand"
@@ -11,48 +15,138 @@ import (
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"io"
+	"math/big"
+	"path/filepath"
+	"strings"
 
 	"time"
 )
 
-// MailService интерфейс для отправки почты
-type MailService interface {
-	SendTempPassword(email, password string) error
+// TeamNotifier отправляет сообщения команде (например, в Telegram)
+type TeamNotifier interface {
+	NotifyTeam(team *models.Team, text string)
+	// EndSessions завершает сессии отвязанных от команды чатов
+	EndSessions(chatIDs []int64)
 }
 
 // TeamService интерфейс сервиса для работы с командами
 type TeamService interface {
-	RegisterTeam(email, name string) error
+	RegisterTeam(email, name, language string) error
 	AuthenticateTeam(email, password string) (*models.Team, error)
 	GetTeamByEmail(email string) (*models.Team, error)
-	LinkTelegramToTeam(email string, telegramID int64) error
+	LinkTelegramToTeam(email string, telegramID int64, displayName string) error
+	CreateInvite(teamID uuid.UUID, telegramID int64) (*models.TeamInvite, error)
+	JoinTeamByInvite(code string, telegramID int64, displayName string) (*models.Team, error)
+	GetTeamMembers(teamID uuid.UUID) ([]models.TeamMember, error)
+	UnlinkTelegram(teamID uuid.UUID) error
+	ChangePassword(teamID uuid.UUID, oldPassword, newPassword string) error
+	RequestPasswordReset(email string) error
+	ResetPasswordWithCode(email, code, newPassword string) error
+	GetCurrentTask(teamID uuid.UUID) (*models.Task, *models.TeamTaskSession, error)
+	GetTeamHistory(teamID uuid.UUID) ([]TaskResult, error)
+	GetTaskResults(companyID, taskID uuid.UUID) ([]TaskResult, error)
+	ResetTeamPassword(teamID uuid.UUID) error
+	GetLeaderboard(contestID uuid.UUID) ([]LeaderboardEntry, error)
+	GetFlaggedSessions(filter repository.FlagFilter) ([]FlaggedSession, error)
+	SendContestReminder(contestID uuid.UUID) (int, error)
+	SendContestResults(contestID uuid.UUID) (int, error)
+	AddAdjustment(teamID uuid.UUID, deltaPoints int, deltaDuration time.Duration, reason, author string) (*models.TeamAdjustment, error)
+	RevokeAdjustment(teamID, adjustmentID uuid.UUID, author string) (*models.TeamAdjustment, error)
+	GetAdjustments(teamID uuid.UUID) ([]models.TeamAdjustment, error)
+	DisqualifyTeam(teamID uuid.UUID, reason string) (*models.Team, error)
+	ReinstateTeam(teamID uuid.UUID) (*models.Team, error)
 	JoinContest(teamID uuid.UUID) (*models.Contest, error)
 	GetTask(teamID uuid.UUID) (*models.Task, error)
 	SubmitAnswer(teamID uuid.UUID, taskID uuid.UUID, answer string) (bool, error)
-	GetUnassignedTeams() ([]models.Team, error)
+	SubmitQRCode(teamID uuid.UUID, code string) (bool, error)
+	RevealHint(teamID uuid.UUID) (*models.TaskHint, int, error)
+	HintCost() int
+	SkipTask(teamID uuid.UUID) (*models.Task, error)
+	GetAvailableCompanies(teamID uuid.UUID) ([]models.Company, error)
+	RequestCompany(teamID, companyID uuid.UUID) (*models.ApprovalRequest, int, error)
+	GetApprovalStatus(teamID uuid.UUID) (*models.ApprovalRequest, int, error)
+	GetApprovalRequests(companyID uuid.UUID, status string) ([]ApprovalRequestInfo, error)
 	ApproveTeam(teamID, companyID uuid.UUID) error
+	RejectTeam(teamID, companyID uuid.UUID, reason string) error
+	GetCompanyDashboard(companyID uuid.UUID) (*CompanyDashboard, error)
 	GetTaskSession(teamID, taskID uuid.UUID) (*models.TeamTaskSession, error)
 	GetTeamByID(teamID uuid.UUID) (*models.Team, error)
-	GetCompanyCredentials(companyID uuid.UUID) (*models.Company, error)
 	GetCompanyIDByTeam(teamID uuid.UUID) (uuid.UUID, error)
 	GetCompanyByID(companyID uuid.UUID) (*models.Company, error)
+	SubmitFileAnswer(teamID uuid.UUID, taskID uuid.UUID, filename string, file io.Reader) (*models.TeamSubmission, error)
+	GetSubmissions(companyID uuid.UUID, status string) ([]models.TeamSubmission, error)
+	GetSubmission(companyID, submissionID uuid.UUID) (*models.TeamSubmission, error)
+	ReviewSubmission(companyID, submissionID uuid.UUID, accepted bool, comment string) (*models.TeamSubmission, error)
+}
+
+// ErrTeamDisqualified возвращается на любые игровые действия дисквалифицированной команды
+var ErrTeamDisqualified = errors.New("команда дисквалифицирована")
+
+// ErrNoTasksLeft возвращается, когда команда прошла все задачи компании
+var ErrNoTasksLeft = errors.New("нет доступных задач")
+
+// CoreStore методы основного репозитория, которыми пользуется сервис команд
+type CoreStore interface {
+	GetCompanyByID(ctx context.Context, id uuid.UUID) (*models.Company, error)
+	GetContestByID(ctx context.Context, id uuid.UUID) (*models.Contest, error)
+	GetTaskByID(ctx context.Context, id uuid.UUID) (*models.Task, error)
+	GetTasksByCompanyID(ctx context.Context, companyID uuid.UUID) ([]models.Task, error)
+	EnqueueEmails(ctx context.Context, emails []*models.OutboxEmail) (int, error)
 }
 
 // TeamServiceImpl имплементация TeamService
 type TeamServiceImpl struct {
-	repo       repository.TeamRepository
-	coreRepo   *repository.Repository
-	db         *gorm.DB
-	mailClient MailService
+	repo      repository.TeamRepository
+	coreRepo  CoreStore
+	db        *gorm.DB
+	notifier  TeamNotifier
+	audit     *audit.Recorder
+	scoring   ScoringConfig
+	antiCheat AntiCheatConfig
 }
 
 // NewTeamService создает новый сервис для работы с командами
-func NewTeamService(teamRepo repository.TeamRepository, coreRepo *repository.Repository, db *gorm.DB, mailClient MailService) *TeamServiceImpl {
+func NewTeamService(teamRepo repository.TeamRepository, coreRepo CoreStore, db *gorm.DB) *TeamServiceImpl {
 	return &TeamServiceImpl{
-		repo:       teamRepo,
-		coreRepo:   coreRepo,
-		db:         db,
-		mailClient: mailClient,
+		repo:      teamRepo,
+		coreRepo:  coreRepo,
+		db:        db,
+		scoring:   LoadScoringConfig(),
+		antiCheat: LoadAntiCheatConfig(),
+	}
+}
+
+// SetNotifier задает получателя уведомлений для команд
+func (s *TeamServiceImpl) SetNotifier(notifier TeamNotifier) {
+	s.notifier = notifier
+}
+
+// SetRecorder подключает журнал аудита для действий команд и самого сервера
+func (s *TeamServiceImpl) SetRecorder(recorder *audit.Recorder) {
+	s.audit = recorder
+}
+
+// record пишет в журнал действие команды, если журнал подключен
+func (s *TeamServiceImpl) record(team *models.Team, action, entityType string, entityID uuid.UUID, before, after interface{}) {
+	if s.audit == nil {
+		return
+	}
+	actor := audit.Actor{ID: team.ID.String(), Name: team.Name, Role: auth.RoleTeam}
+	s.audit.RecordAs(context.Background(), actor, action, entityType, entityID, before, after)
+}
+
+// recordSystem пишет в журнал действие, которое сервер выполнил сам
+func (s *TeamServiceImpl) recordSystem(action, entityType string, entityID uuid.UUID, before, after interface{}) {
+	if s.audit != nil {
+		s.audit.RecordAs(context.Background(), audit.SystemActor, action, entityType, entityID, before, after)
+	}
+}
+
+// notifyTeam отправляет сообщение команде, если уведомления настроены
+func (s *TeamServiceImpl) notifyTeam(team *models.Team, text string) {
+	if s.notifier != nil {
+		s.notifier.NotifyTeam(team, text)
 	}
 }
 
@@ -67,7 +161,7 @@ func GenerateTemporaryPassword() (string, error) {
 }
 
 // RegisterTeam регистрирует новую команду
-func (s *TeamServiceImpl) RegisterTeam(email, name string) error {
+func (s *TeamServiceImpl) RegisterTeam(email, name, language string) error {
 	// Генерируем временный пароль
 	tempPassword, err := GenerateTemporaryPassword()
 	if err != nil {
@@ -81,23 +175,16 @@ func (s *TeamServiceImpl) RegisterTeam(email, name string) error {
 	}
 
 	team := &models.Team{
-		Name:         name,
-		Email:        email,
-		PasswordHash: string(hashedPassword),
-	}
-
-	if err := s.repo.Create(team); err != nil {
-		return err
-	}
-
-	// Отправляем пароль на почту
-	if err := s.mailClient.SendTempPassword(email, tempPassword); err != nil {
-		// Если отправка не удалась - удаляем команду
-		_ = s.repo.Delete(team.ID)
-		return fmt.Errorf("не удалось отправить пароль: %v", err)
+		Name:          name,
+		Email:         email,
+		PasswordHash:  string(hashedPassword),
+		ResetRequired: true,
+		Status:        models.TeamStatusActive,
+		Language:      mail.NormalizeLanguage(language),
 	}
 
-	return nil
+	// Письмо с паролем ставится в очередь вместе с командой и отправляется в фоне
+	return s.repo.CreateWithEmail(team, mail.Welcome(email, team.Language, name, tempPassword))
 }
 
 // AuthenticateTeam аутентифицирует команду по email и паролю
@@ -116,21 +203,300 @@ func (s *TeamServiceImpl) AuthenticateTeam(email, password string) (*models.Team
 	return team, nil
 }
 
-// LinkTelegramToTeam связывает Telegram ID с командой
-func (s *TeamServiceImpl) LinkTelegramToTeam(email string, telegramID int64) error {
+// LinkTelegramToTeam связывает Telegram ID с командой.
+// Первый вошедший по паролю аккаунт становится капитаном, остальные
+// участники присоединяются по коду приглашения капитана.
+func (s *TeamServiceImpl) LinkTelegramToTeam(email string, telegramID int64, displayName string) error {
 	team, err := s.repo.FindByEmail(email)
 	if err != nil {
 		return errors.New("team not found")
 	}
 
 	// Проверяем, не привязан ли уже этот Telegram ID к другой команде
-	existingTeam, err := s.repo.FindByTelegramID(telegramID)
-	if err == nil && existingTeam.ID != team.ID {
-		return errors.New("this telegram ID is already linked to another team")
+	if member, err := s.repo.FindMemberByTelegramID(telegramID); err == nil {
+		if member.TeamID != team.ID {
+			return errors.New("this telegram ID is already linked to another team")
+		}
+		return nil
+	}
+
+	members, err := s.repo.GetMembers(team.ID)
+	if err != nil {
+		return err
+	}
+	if len(members) > 0 {
+		return errors.New("к команде уже привязан капитан. Попросите у него код приглашения (/invite) и войдите через /join")
+	}
+
+	if err := s.repo.AddMember(&models.TeamMember{
+		TeamID:      team.ID,
+		TelegramID:  telegramID,
+		DisplayName: displayName,
+		Role:        models.MemberRoleCaptain,
+	}); err != nil {
+		return err
 	}
 
-	// Связываем Telegram ID с командой
-	team.TelegramID = telegramID
+	// Связываем Telegram ID капитана с командой
+	team.TelegramID = &telegramID
+	return s.repo.Update(team)
+}
+
+// generateInviteCode генерирует код приглашения в команду
+func generateInviteCode() (string, error) {
+	const chars = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = chars[int(b[i])%len(chars)]
+	}
+	return string(b), nil
+}
+
+// CreateInvite выдает одноразовый код приглашения от имени капитана команды
+func (s *TeamServiceImpl) CreateInvite(teamID uuid.UUID, telegramID int64) (*models.TeamInvite, error) {
+	member, err := s.repo.FindMemberByTelegramID(telegramID)
+	if err != nil || member.TeamID != teamID {
+		return nil, errors.New("вы не состоите в этой команде")
+	}
+
+	if member.Role != models.MemberRoleCaptain {
+		return nil, errors.New("приглашать участников может только капитан")
+	}
+
+	code, err := generateInviteCode()
+	if err != nil {
+		return nil, err
+	}
+
+	invite := &models.TeamInvite{
+		TeamID:    teamID,
+		Code:      code,
+		CreatedBy: telegramID,
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+	}
+	if err := s.repo.CreateInvite(invite); err != nil {
+		return nil, err
+	}
+	return invite, nil
+}
+
+// JoinTeamByInvite добавляет Telegram-аккаунт в команду по коду приглашения
+func (s *TeamServiceImpl) JoinTeamByInvite(code string, telegramID int64, displayName string) (*models.Team, error) {
+	invite, err := s.repo.FindInviteByCode(strings.ToUpper(strings.TrimSpace(code)))
+	if err != nil || invite.UsedAt != nil || time.Now().After(invite.ExpiresAt) {
+		return nil, errors.New("код приглашения недействителен")
+	}
+
+	if member, err := s.repo.FindMemberByTelegramID(telegramID); err == nil {
+		if member.TeamID != invite.TeamID {
+			return nil, errors.New("this telegram ID is already linked to another team")
+		}
+		return s.repo.FindByID(member.TeamID)
+	}
+
+	team, err := s.repo.FindByID(invite.TeamID)
+	if err != nil {
+		return nil, errors.New("team not found")
+	}
+
+	err = s.repo.UseInvite(invite, &models.TeamMember{
+		TeamID:      team.ID,
+		TelegramID:  telegramID,
+		DisplayName: displayName,
+		Role:        models.MemberRoleMember,
+	})
+	if errors.Is(err, repository.ErrInviteUsed) {
+		return nil, errors.New("код приглашения недействителен")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.record(team, "team.member_join", "team", team.ID, nil, map[string]interface{}{"telegram_id": telegramID, "display_name": displayName})
+	s.notifyTeam(team, fmt.Sprintf("👥 %s присоединился к команде", displayName))
+
+	return team, nil
+}
+
+// GetTeamMembers возвращает участников команды
+func (s *TeamServiceImpl) GetTeamMembers(teamID uuid.UUID) ([]models.TeamMember, error) {
+	return s.repo.GetMembers(teamID)
+}
+
+// UnlinkTelegram отвязывает от команды все Telegram-аккаунты и завершает их сессии в боте
+func (s *TeamServiceImpl) UnlinkTelegram(teamID uuid.UUID) error {
+	team, err := s.repo.FindByID(teamID)
+	if err != nil {
+		return errors.New("team not found")
+	}
+
+	members, err := s.repo.GetMembers(team.ID)
+	if err != nil {
+		return err
+	}
+	chats := make([]int64, 0, len(members)+1)
+	for _, member := range members {
+		chats = append(chats, member.TelegramID)
+	}
+	if team.TelegramID != nil {
+		chats = append(chats, *team.TelegramID)
+	}
+
+	if err := s.repo.RemoveMembers(team.ID); err != nil {
+		return err
+	}
+
+	if s.notifier != nil {
+		s.notifier.EndSessions(chats)
+	}
+	return nil
+}
+
+// MinPasswordLength минимальная длина пароля команды
+const MinPasswordLength = 8
+
+// ChangePassword меняет пароль команды после проверки текущего
+func (s *TeamServiceImpl) ChangePassword(teamID uuid.UUID, oldPassword, newPassword string) error {
+	team, err := s.repo.FindByID(teamID)
+	if err != nil {
+		return errors.New("team not found")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(team.PasswordHash), []byte(oldPassword)); err != nil {
+		return errors.New("invalid old password")
+	}
+
+	if err := s.setPassword(team, newPassword); err != nil {
+		return err
+	}
+	s.record(team, "team.change_password", "team", team.ID, nil, nil)
+	return nil
+}
+
+const (
+	// resetCodeTTL время действия кода сброса пароля
+	resetCodeTTL = 15 * time.Minute
+	// resetCodeCooldown минимальный интервал между письмами с кодом на один email
+	resetCodeCooldown = time.Minute
+	// maxResetCodeAttempts число неверных вводов, после которого код сгорает до конца срока
+	maxResetCodeAttempts = 5
+)
+
+// ErrInvalidResetCode неверный, просроченный или сгоревший код сброса пароля
+var ErrInvalidResetCode = errors.New("неверный или просроченный код")
+
+// RequestPasswordReset отправляет на почту команды одноразовый код для сброса пароля.
+// Если команда не найдена, ошибка не возвращается, чтобы не раскрывать email.
+func (s *TeamServiceImpl) RequestPasswordReset(email string) error {
+	team, err := s.repo.FindByEmail(email)
+	if err != nil {
+		return nil
+	}
+
+	// Пока действует прежний код, счетчик неверных попыток сохраняется: повторный запрос
+	// не дает новых попыток, а новый код отправляется не чаще раза в resetCodeCooldown.
+	// Ответ не меняется, чтобы не раскрывать, существует ли команда.
+	if team.ResetCodeExpiresAt != nil && time.Now().Before(*team.ResetCodeExpiresAt) {
+		issuedAt := team.ResetCodeExpiresAt.Add(-resetCodeTTL)
+		if team.ResetCodeAttempts >= maxResetCodeAttempts || time.Since(issuedAt) < resetCodeCooldown {
+			return nil
+		}
+	} else {
+		team.ResetCodeAttempts = 0
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return err
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(resetCodeTTL)
+	team.ResetCodeHash = string(hash)
+	team.ResetCodeExpiresAt = &expiresAt
+	return s.repo.UpdateWithEmail(team, mail.ResetCode(team.Email, team.Language, code, int(resetCodeTTL.Minutes())))
+}
+
+// ResetPasswordWithCode устанавливает новый пароль по коду из письма
+func (s *TeamServiceImpl) ResetPasswordWithCode(email, code, newPassword string) error {
+	invalid := ErrInvalidResetCode
+
+	team, err := s.repo.FindByEmail(email)
+	if err != nil {
+		return invalid
+	}
+
+	if team.ResetCodeHash == "" || team.ResetCodeExpiresAt == nil || time.Now().After(*team.ResetCodeExpiresAt) {
+		return invalid
+	}
+
+	// После нескольких неверных попыток код сгорает
+	if team.ResetCodeAttempts >= maxResetCodeAttempts {
+		return invalid
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(team.ResetCodeHash), []byte(code)); err != nil {
+		team.ResetCodeAttempts++
+		_ = s.repo.Update(team)
+		return invalid
+	}
+
+	if err := s.setPassword(team, newPassword); err != nil {
+		return err
+	}
+	s.record(team, "team.reset_password_by_code", "team", team.ID, nil, nil)
+	return nil
+}
+
+// ResetTeamPassword выдает команде новый временный пароль и отправляет его на почту
+func (s *TeamServiceImpl) ResetTeamPassword(teamID uuid.UUID) error {
+	team, err := s.repo.FindByID(teamID)
+	if err != nil {
+		return errors.New("team not found")
+	}
+
+	tempPassword, err := GenerateTemporaryPassword()
+	if err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(tempPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	team.PasswordHash = string(hash)
+	team.ResetRequired = true
+	team.TokenVersion++
+	return s.repo.UpdateWithEmail(team, mail.PasswordReset(team.Email, team.Language, team.Name, tempPassword))
+}
+
+// setPassword хеширует и сохраняет новый пароль команды, сбрасывая код восстановления
+func (s *TeamServiceImpl) setPassword(team *models.Team, newPassword string) error {
+	if len(newPassword) < MinPasswordLength {
+		return fmt.Errorf("пароль должен содержать не менее %d символов", MinPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	team.PasswordHash = string(hash)
+	team.ResetRequired = false
+	team.ResetCodeHash = ""
+	team.ResetCodeExpiresAt = nil
+	team.ResetCodeAttempts = 0
+	// Токены, выданные со старым паролем, перестают действовать
+	team.TokenVersion++
 	return s.repo.Update(team)
 }
 
@@ -142,6 +508,10 @@ func (s *TeamServiceImpl) JoinContest(teamID uuid.UUID) (*models.Contest, error)
 		return nil, errors.New("team not found")
 	}
 
+	if team.Status == models.TeamStatusDisqualified {
+		return nil, ErrTeamDisqualified
+	}
+
 	// Получаем активный контест
 	contest, err := s.repo.GetActiveContest()
 	if err != nil {
@@ -150,42 +520,90 @@ func (s *TeamServiceImpl) JoinContest(teamID uuid.UUID) (*models.Contest, error)
 
 	// Привязываем команду к контесту
 	team.ContestID = &contest.ID
+	if team.TaskSeed == 0 {
+		if team.TaskSeed, err = generateTaskSeed(); err != nil {
+			return nil, err
+		}
+	}
 	if err := s.repo.Update(team); err != nil {
 		return nil, err
 	}
+	s.record(team, "team.join_contest", "contest", contest.ID, nil, nil)
 
 	return contest, nil
 }
 
-// GetTask возвращает задачу для команды
+// GetTask возвращает задачу для команды. Пока текущая задача не завершена,
+// возвращается она же: новая выдается только после ответа, отправки файла на проверку,
+// пропуска или истечения времени.
 func (s *TeamServiceImpl) GetTask(teamID uuid.UUID) (*models.Task, error) {
 	team, err := s.repo.FindByID(teamID)
 	if err != nil {
 		return nil, errors.New("team not found")
 	}
 
+	if team.Status == models.TeamStatusDisqualified {
+		return nil, ErrTeamDisqualified
+	}
+
 	// Проверяем, что команда участвует в контесте
 	if team.ContestID == nil || team.CompanyID == nil {
 		return nil, errors.New("team is not assigned to contest or company")
 	}
 
-	// Получаем ID задач, которые команда уже решала
-	usedIDs, _ := s.repo.GetUsedTaskIDs(team.ID)
-
-	// Получаем новую уникальную задачу
-	var task models.Task
-	query := s.db.Where("contest_id = ? AND company_id = ?", *team.ContestID, *team.CompanyID)
+	// Пока текущая задача не решена, не пропущена и не истекла, новую не выдаем
+	if team.CurrentTaskID != nil {
+		current, err := s.currentUnfinishedTask(team)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil {
+			return current, nil
+		}
+	}
 
-	if len(usedIDs) > 0 {
-		query = query.Where("id NOT IN ?", usedIDs)
+	contest, err := s.coreRepo.GetContestByID(context.TODO(), *team.ContestID)
+	if err != nil {
+		return nil, errors.New("contest not found")
 	}
 
-	err = query.First(&task).Error
+	// Seed сохраняется в команде, чтобы порядок можно было воспроизвести
+	if contest.TaskOrder == models.TaskOrderShuffled && team.TaskSeed == 0 {
+		if team.TaskSeed, err = generateTaskSeed(); err != nil {
+			return nil, err
+		}
+	}
 
+	tasks, err := s.repo.GetCompanyTasks(*team.ContestID, *team.CompanyID)
 	if err != nil {
 		return nil, fmt.Errorf("нет доступных задач: %v", err)
 	}
 
+	// Получаем ID задач, которые команда уже решала
+	usedIDs, _ := s.repo.GetUsedTaskIDs(team.ID)
+	used := make(map[uuid.UUID]bool, len(usedIDs))
+	for _, id := range usedIDs {
+		used[id] = true
+	}
+
+	// Выбираем первую нерешенную задачу в порядке контеста
+	var task *models.Task
+	for _, candidate := range orderTasks(tasks, contest.TaskOrder, team.TaskSeed) {
+		if !used[candidate.ID] {
+			task = &candidate
+			break
+		}
+	}
+
+	if task == nil {
+		// Все задачи компании пройдены, место у компании освобождается для следующей команды
+		if err := s.repo.ReleaseTeam(team.ID); err != nil {
+			return nil, err
+		}
+		s.recordSystem("team.release", "team", team.ID, map[string]interface{}{"company_id": team.CompanyID}, nil)
+		return nil, ErrNoTasksLeft
+	}
+
 	// Обновляем текущую задачу команды
 	team.CurrentTaskID = &task.ID
 	if err := s.repo.Update(team); err != nil {
@@ -202,10 +620,63 @@ func (s *TeamServiceImpl) GetTask(teamID uuid.UUID) (*models.Task, error) {
 	}
 	_ = s.repo.CreateTaskSession(session)
 
-	return &task, nil
+	return task, nil
+}
+
+// currentUnfinishedTask возвращает текущую задачу команды, если ее сессия еще идет.
+// Задача с файлом на проверке для выдачи считается сданной: очки начислит проверка компании,
+// а команда тем временем получает следующую. Истекшая сессия без файла закрывается
+// как нерешенная. В обоих случаях возвращается nil.
+func (s *TeamServiceImpl) currentUnfinishedTask(team *models.Team) (*models.Task, error) {
+	session, err := s.repo.GetTaskSession(team.ID, *team.CurrentTaskID)
+	if err != nil || session.Finished {
+		return nil, nil
+	}
+
+	if _, err := s.repo.GetPendingSubmissionBySession(session.ID); err == nil {
+		return nil, nil
+	}
+	if time.Since(session.StartTime) > 10*time.Minute {
+		if err := s.finishSession(team, session, false, session.StartTime.Add(10*time.Minute)); err != nil {
+			return nil, err
+		}
+		if err := s.repo.UpdateTaskSession(session); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	task, err := s.coreRepo.GetTaskByID(context.TODO(), session.TaskID)
+	if err != nil {
+		return nil, errors.New("task not found")
+	}
+	return task, nil
+}
+
+// GetCurrentTask возвращает текущую задачу команды и ее сессию
+func (s *TeamServiceImpl) GetCurrentTask(teamID uuid.UUID) (*models.Task, *models.TeamTaskSession, error) {
+	team, err := s.repo.FindByID(teamID)
+	if err != nil {
+		return nil, nil, errors.New("team not found")
+	}
+
+	if team.CurrentTaskID == nil {
+		return nil, nil, errors.New("у команды нет текущей задачи")
+	}
+
+	task, err := s.coreRepo.GetTaskByID(context.TODO(), *team.CurrentTaskID)
+	if err != nil {
+		return nil, nil, errors.New("task not found")
+	}
+
+	session, err := s.repo.GetTaskSession(team.ID, task.ID)
+	if err != nil {
+		return nil, nil, errors.New("task session not found")
+	}
+
+	return task, session, nil
 }
 
-// SubmitAnswer проверяет ответ команды на задачу
 // SubmitAnswer проверяет ответ команды на задачу
 func (s *TeamServiceImpl) SubmitAnswer(teamID uuid.UUID, taskID uuid.UUID, answer string) (bool, error) {
 	team, err := s.repo.FindByID(teamID)
@@ -213,6 +684,10 @@ func (s *TeamServiceImpl) SubmitAnswer(teamID uuid.UUID, taskID uuid.UUID, answe
 		return false, errors.New("team not found")
 	}
 
+	if team.Status == models.TeamStatusDisqualified {
+		return false, ErrTeamDisqualified
+	}
+
 	if team.CurrentTaskID == nil || *team.CurrentTaskID != taskID {
 		return false, errors.New("team is not working on this task")
 	}
@@ -222,6 +697,10 @@ func (s *TeamServiceImpl) SubmitAnswer(teamID uuid.UUID, taskID uuid.UUID, answe
 		return false, errors.New("task not found")
 	}
 
+	if task.AnswerType == models.AnswerTypePhoto {
+		return false, errors.New("для этой задачи нужно отправить фото или файл")
+	}
+
 	session, err := s.repo.GetTaskSession(team.ID, taskID)
 	if err != nil {
 		return false, errors.New("task session not found")
@@ -235,35 +714,9 @@ func (s *TeamServiceImpl) SubmitAnswer(teamID uuid.UUID, taskID uuid.UUID, answe
 	isCorrect := task.CorrectAnswer == answer
 	session.IsCorrect = isCorrect
 
-	// === ДОБАВЛЕНО: расчёт и накопление времени, если задача завершена ===
 	if isCorrect || session.Attempts >= 3 || time.Since(session.StartTime) > 10*time.Minute {
-		session.Finished = true
-
-		// === Вычисляем фактическое время выполнения задачи ===
-		endTime := time.Now()
-		duration := endTime.Sub(session.StartTime)
-		if duration > 10*time.Minute {
-			duration = 10 * time.Minute // Лимит
-		}
-
-		// === Обновляем очки, если ответ правильный ===
-		if isCorrect {
-			team.Points += 1
-		}
-
-		// === Правильно накапливаем время в команде ===
-		// Преобразуем текущее TotalDuration в time.Duration
-		currentDuration := team.TotalDuration.Duration()
-
-		// Добавляем новую продолжительность
-		newTotalDuration := currentDuration + duration
-
-		// Обновляем TotalDuration команды
-		team.TotalDuration = models.PGInterval(newTotalDuration)
-
-		err := s.repo.Update(team)
-		if err != nil {
-			return false, errors.New("failed to update team with duration")
+		if err := s.finishSession(team, session, isCorrect, time.Now()); err != nil {
+			return false, err
 		}
 	}
 	_ = s.repo.UpdateTaskSession(session)
@@ -275,24 +728,328 @@ func (s *TeamServiceImpl) SubmitAnswer(teamID uuid.UUID, taskID uuid.UUID, answe
 		IsCorrect: isCorrect,
 	}
 	_ = s.repo.SaveAnswer(teamAnswer)
+	s.checkAnswer(team, task, session, answer, isCorrect)
 
 	return isCorrect, nil
 }
 
-func (s *TeamServiceImpl) GetTeamByEmail(email string) (*models.Team, error) {
-	team, err := s.repo.FindByEmail(email)
+// SubmitQRCode засчитывает код из отсканированного QR как ответ на текущую задачу команды
+func (s *TeamServiceImpl) SubmitQRCode(teamID uuid.UUID, code string) (bool, error) {
+	team, err := s.repo.FindByID(teamID)
 	if err != nil {
-		return nil, fmt.Errorf("team not found")
+		return false, errors.New("team not found")
 	}
-	return team, nil
+
+	if team.CurrentTaskID == nil {
+		return false, errors.New("у команды нет текущей задачи")
+	}
+
+	task, err := s.coreRepo.GetTaskByID(context.TODO(), *team.CurrentTaskID)
+	if err != nil {
+		return false, errors.New("task not found")
+	}
+
+	if task.AnswerType != models.AnswerTypeQR {
+		return false, errors.New("текущая задача не требует QR-кода")
+	}
+
+	return s.SubmitAnswer(team.ID, task.ID, code)
+}
+
+// RevealHint открывает следующую подсказку к текущей задаче команды.
+// Возвращает подсказку и количество оставшихся подсказок.
+func (s *TeamServiceImpl) RevealHint(teamID uuid.UUID) (*models.TaskHint, int, error) {
+	team, err := s.repo.FindByID(teamID)
+	if err != nil {
+		return nil, 0, errors.New("team not found")
+	}
+
+	if team.Status == models.TeamStatusDisqualified {
+		return nil, 0, ErrTeamDisqualified
+	}
+
+	if team.CurrentTaskID == nil {
+		return nil, 0, errors.New("у команды нет текущей задачи")
+	}
+
+	session, err := s.repo.GetTaskSession(team.ID, *team.CurrentTaskID)
+	if err != nil {
+		return nil, 0, errors.New("task session not found")
+	}
+
+	if session.Finished {
+		return nil, 0, errors.New("задача уже завершена")
+	}
+
+	hints, err := s.repo.GetTaskHints(session.TaskID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if session.HintsUsed >= len(hints) {
+		return nil, 0, errors.New("подсказок больше нет")
+	}
+
+	hint := hints[session.HintsUsed]
+	session.HintsUsed++
+	if err := s.repo.UpdateTaskSession(session); err != nil {
+		return nil, 0, err
+	}
+
+	return &hint, len(hints) - session.HintsUsed, nil
+}
+
+// HintCost возвращает количество очков, снимаемых за одну подсказку
+func (s *TeamServiceImpl) HintCost() int {
+	return s.scoring.HintCost
+}
+
+// SkipTask завершает текущую задачу команды как нерешенную со штрафом
+// и сразу выдает следующую. Если задач больше нет, возвращает nil без ошибки.
+func (s *TeamServiceImpl) SkipTask(teamID uuid.UUID) (*models.Task, error) {
+	team, err := s.repo.FindByID(teamID)
+	if err != nil {
+		return nil, errors.New("team not found")
+	}
+
+	if team.Status == models.TeamStatusDisqualified {
+		return nil, ErrTeamDisqualified
+	}
+
+	if team.CurrentTaskID == nil {
+		return nil, errors.New("у команды нет текущей задачи")
+	}
+
+	session, err := s.repo.GetTaskSession(team.ID, *team.CurrentTaskID)
+	if err != nil {
+		return nil, errors.New("task session not found")
+	}
+
+	if session.Finished {
+		return nil, errors.New("задача уже завершена")
+	}
+
+	if _, err := s.repo.GetPendingSubmissionBySession(session.ID); err == nil {
+		return nil, errors.New("решение уже отправлено на проверку")
+	}
+
+	session.Skipped = true
+	if err := s.finishSession(team, session, false, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateTaskSession(session); err != nil {
+		return nil, err
+	}
+	s.record(team, "task.skip", "task", session.TaskID, nil, map[string]interface{}{
+		"session_id": session.ID,
+		"points":     session.Points,
+		"duration":   session.Duration.String(),
+	})
+
+	next, err := s.GetTask(team.ID)
+	if errors.Is(err, ErrNoTasksLeft) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return next, nil
 }
 
-func (s *TeamServiceImpl) GetUnassignedTeams() ([]models.Team, error) {
-	return s.repo.GetUnassignedTeams()
+// finishSession завершает сессию задачи и начисляет команде очки и время.
+// Сессия не сохраняется, это остается на вызывающей стороне.
+func (s *TeamServiceImpl) finishSession(team *models.Team, session *models.TeamTaskSession, isCorrect bool, endTime time.Time) error {
+	session.Finished = true
+	session.IsCorrect = isCorrect
+	session.FinishedAt = &endTime
+
+	// === Вычисляем фактическое время выполнения задачи ===
+	duration := endTime.Sub(session.StartTime)
+	if duration > 10*time.Minute {
+		duration = 10 * time.Minute // Лимит
+	}
+
+	// === Очки за правильный ответ ===
+	points := 0
+	if isCorrect {
+		points += 1
+	}
+
+	// === Снимаем очки за открытые подсказки ===
+	points -= session.HintsUsed * s.scoring.HintCost
+
+	// === Штраф за пропуск задачи ===
+	if session.Skipped {
+		points -= s.scoring.SkipPenaltyPoints
+		duration += s.scoring.SkipPenaltyTime
+	}
+
+	session.Points = points
+	session.Duration = models.PGInterval(duration)
+
+	// === Накапливаем очки и время в команде ===
+	team.Points += points
+	team.TotalDuration = models.PGInterval(team.TotalDuration.Duration() + duration)
+
+	if err := s.repo.Update(team); err != nil {
+		return errors.New("failed to update team with duration")
+	}
+	return nil
+}
+
+// SubmitFileAnswer сохраняет фото или документ команды и ставит его в очередь на проверку компанией
+func (s *TeamServiceImpl) SubmitFileAnswer(teamID uuid.UUID, taskID uuid.UUID, filename string, file io.Reader) (*models.TeamSubmission, error) {
+	team, err := s.repo.FindByID(teamID)
+	if err != nil {
+		return nil, errors.New("team not found")
+	}
+
+	if team.Status == models.TeamStatusDisqualified {
+		return nil, ErrTeamDisqualified
+	}
+
+	if team.CurrentTaskID == nil || *team.CurrentTaskID != taskID {
+		return nil, errors.New("team is not working on this task")
+	}
+
+	task, err := s.coreRepo.GetTaskByID(context.TODO(), taskID)
+	if err != nil {
+		return nil, errors.New("task not found")
+	}
+
+	if task.AnswerType != models.AnswerTypePhoto {
+		return nil, errors.New("для этой задачи нужно отправить текстовый ответ")
+	}
+
+	session, err := s.repo.GetTaskSession(team.ID, taskID)
+	if err != nil {
+		return nil, errors.New("task session not found")
+	}
+
+	if session.Finished || session.Attempts >= 3 || time.Since(session.StartTime) > 10*time.Minute {
+		return nil, errors.New("Task is finished or timed out")
+	}
+
+	if _, err := s.repo.GetPendingSubmissionBySession(session.ID); err == nil {
+		return nil, errors.New("решение уже отправлено на проверку")
+	}
+
+	submission := &models.TeamSubmission{
+		ID:        uuid.New(),
+		TeamID:    team.ID,
+		TaskID:    task.ID,
+		CompanyID: task.CompanyID,
+		SessionID: session.ID,
+		Status:    models.SubmissionPending,
+	}
+
+	path, err := pkg.SaveSubmissionFile(file, submission.ID, filename)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось сохранить файл: %v", err)
+	}
+	submission.FileName = filepath.Base(path)
+
+	if err := s.repo.CreateSubmission(submission); err != nil {
+		_ = pkg.DeleteSubmissionFiles(submission.ID)
+		return nil, err
+	}
+
+	session.Attempts++
+	_ = s.repo.UpdateTaskSession(session)
+
+	// Результат проверки еще не известен, но сфотографировать станцию за секунды после
+	// выдачи задачи нельзя, поэтому скорость отправки проверяется как у правильного ответа
+	s.checkAnswer(team, task, session, "", true)
+
+	return submission, nil
 }
 
-func (s *TeamServiceImpl) ApproveTeam(teamID, companyID uuid.UUID) error {
-	return s.repo.ApproveTeam(teamID, companyID)
+// GetSubmissions возвращает присланные командами файлы по задачам компании
+func (s *TeamServiceImpl) GetSubmissions(companyID uuid.UUID, status string) ([]models.TeamSubmission, error) {
+	return s.repo.GetSubmissionsByCompany(companyID, status)
+}
+
+// GetSubmission возвращает присланный файл, если он относится к задаче компании
+func (s *TeamServiceImpl) GetSubmission(companyID, submissionID uuid.UUID) (*models.TeamSubmission, error) {
+	submission, err := s.repo.GetSubmissionByID(submissionID)
+	if err != nil || submission.CompanyID != companyID {
+		return nil, errors.New("submission not found")
+	}
+	return submission, nil
+}
+
+// ReviewSubmission принимает или отклоняет присланный командой файл,
+// завершает сессию задачи и уведомляет команду о результате
+func (s *TeamServiceImpl) ReviewSubmission(companyID, submissionID uuid.UUID, accepted bool, comment string) (*models.TeamSubmission, error) {
+	submission, err := s.GetSubmission(companyID, submissionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if submission.Status != models.SubmissionPending {
+		return nil, errors.New("submission already reviewed")
+	}
+
+	team, err := s.repo.FindByID(submission.TeamID)
+	if err != nil {
+		return nil, errors.New("team not found")
+	}
+
+	session, err := s.repo.GetTaskSessionByID(submission.SessionID)
+	if err != nil {
+		return nil, errors.New("task session not found")
+	}
+
+	if session.Finished {
+		return nil, errors.New("task session is already finished")
+	}
+
+	// Время выполнения считается по моменту отправки, а не проверки
+	if err := s.finishSession(team, session, accepted, submission.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateTaskSession(session); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	submission.Comment = comment
+	submission.ReviewedAt = &now
+	if accepted {
+		submission.Status = models.SubmissionAccepted
+	} else {
+		submission.Status = models.SubmissionRejected
+	}
+	if err := s.repo.UpdateSubmission(submission); err != nil {
+		return nil, err
+	}
+
+	_ = s.repo.SaveAnswer(&models.TeamAnswer{
+		TeamID:    submission.TeamID,
+		TaskID:    submission.TaskID,
+		Answer:    submission.FileName,
+		IsCorrect: accepted,
+		CreatedAt: submission.CreatedAt,
+	})
+
+	text := "✅ Ваше решение принято! Очки начислены."
+	if !accepted {
+		text = "❌ Ваше решение отклонено."
+	}
+	if comment != "" {
+		text += "\nКомментарий: " + comment
+	}
+	s.notifyTeam(team, text)
+
+	return submission, nil
+}
+
+func (s *TeamServiceImpl) GetTeamByEmail(email string) (*models.Team, error) {
+	team, err := s.repo.FindByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("team not found")
+	}
+	return team, nil
 }
 
 func (s *TeamServiceImpl) GetTaskSession(teamID, taskID uuid.UUID) (*models.TeamTaskSession, error) {
@@ -302,9 +1059,6 @@ func (s *TeamServiceImpl) GetTaskSession(teamID, taskID uuid.UUID) (*models.Team
 func (s *TeamServiceImpl) GetTeamByID(teamID uuid.UUID) (*models.Team, error) {
 	return s.repo.FindByID(teamID)
 }
-func (s *TeamServiceImpl) GetCompanyCredentials(companyID uuid.UUID) (*models.Company, error) {
-	return s.coreRepo.GetCompanyByID(context.Background(), companyID)
-}
 func (s *TeamServiceImpl) GetCompanyIDByTeam(teamID uuid.UUID) (uuid.UUID, error) {
 	team, err := s.repo.FindByID(teamID)
 	if err != nil || team.CompanyID == nil {
@@ -0,0 +1,381 @@
+package service
+
+import (
+	"Cyber-chase/internal/models"
+	"Cyber-chase/internal/repository"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var errNotFound = errors.New("record not found")
+
+// fakeTeamRepo хранит команды, сессии и присланные файлы в памяти.
+// Методы, которые в тестах не вызываются, остаются от встроенного nil-интерфейса.
+type fakeTeamRepo struct {
+	repository.TeamRepository
+
+	teams       map[uuid.UUID]models.Team
+	tasks       []models.Task
+	sessions    []*models.TeamTaskSession
+	submissions []*models.TeamSubmission
+	answers     []models.TeamAnswer
+	flags       []models.AnswerFlag
+	approvals   []models.ApprovalRequest
+	released    []uuid.UUID
+}
+
+func (r *fakeTeamRepo) FindByID(id uuid.UUID) (*models.Team, error) {
+	team, ok := r.teams[id]
+	if !ok {
+		return nil, errNotFound
+	}
+	return &team, nil
+}
+
+func (r *fakeTeamRepo) Update(team *models.Team) error {
+	r.teams[team.ID] = *team
+	return nil
+}
+
+func (r *fakeTeamRepo) GetCompanyTasks(contestID, companyID uuid.UUID) ([]models.Task, error) {
+	var tasks []models.Task
+	for _, task := range r.tasks {
+		if task.ContestID == contestID && task.CompanyID == companyID {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+func (r *fakeTeamRepo) GetUsedTaskIDs(teamID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for _, session := range r.sessions {
+		if session.TeamID == teamID {
+			ids = append(ids, session.TaskID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *fakeTeamRepo) CreateTaskSession(session *models.TeamTaskSession) error {
+	session.ID = uuid.New()
+	stored := *session
+	r.sessions = append(r.sessions, &stored)
+	return nil
+}
+
+func (r *fakeTeamRepo) GetTaskSession(teamID, taskID uuid.UUID) (*models.TeamTaskSession, error) {
+	for _, session := range r.sessions {
+		if session.TeamID == teamID && session.TaskID == taskID {
+			found := *session
+			return &found, nil
+		}
+	}
+	return nil, errNotFound
+}
+
+func (r *fakeTeamRepo) GetTaskSessionByID(id uuid.UUID) (*models.TeamTaskSession, error) {
+	for _, session := range r.sessions {
+		if session.ID == id {
+			found := *session
+			return &found, nil
+		}
+	}
+	return nil, errNotFound
+}
+
+func (r *fakeTeamRepo) UpdateTaskSession(session *models.TeamTaskSession) error {
+	for i, stored := range r.sessions {
+		if stored.ID == session.ID {
+			updated := *session
+			r.sessions[i] = &updated
+			return nil
+		}
+	}
+	return errNotFound
+}
+
+func (r *fakeTeamRepo) CreateSubmission(submission *models.TeamSubmission) error {
+	submission.CreatedAt = time.Now()
+	stored := *submission
+	r.submissions = append(r.submissions, &stored)
+	return nil
+}
+
+func (r *fakeTeamRepo) GetSubmissionByID(id uuid.UUID) (*models.TeamSubmission, error) {
+	for _, submission := range r.submissions {
+		if submission.ID == id {
+			found := *submission
+			return &found, nil
+		}
+	}
+	return nil, errNotFound
+}
+
+func (r *fakeTeamRepo) GetPendingSubmissionBySession(sessionID uuid.UUID) (*models.TeamSubmission, error) {
+	for _, submission := range r.submissions {
+		if submission.SessionID == sessionID && submission.Status == models.SubmissionPending {
+			found := *submission
+			return &found, nil
+		}
+	}
+	return nil, errNotFound
+}
+
+func (r *fakeTeamRepo) UpdateSubmission(submission *models.TeamSubmission) error {
+	for i, stored := range r.submissions {
+		if stored.ID == submission.ID {
+			updated := *submission
+			r.submissions[i] = &updated
+			return nil
+		}
+	}
+	return errNotFound
+}
+
+func (r *fakeTeamRepo) SaveAnswer(answer *models.TeamAnswer) error {
+	r.answers = append(r.answers, *answer)
+	return nil
+}
+
+func (r *fakeTeamRepo) CreateFlag(flag *models.AnswerFlag) error {
+	r.flags = append(r.flags, *flag)
+	return nil
+}
+
+func (r *fakeTeamRepo) GetActiveApprovalRequest(teamID uuid.UUID) (*models.ApprovalRequest, error) {
+	for i := len(r.approvals) - 1; i >= 0; i-- {
+		request := r.approvals[i]
+		if request.TeamID == teamID && (request.Status == models.ApprovalPending || request.Status == models.ApprovalApproved) {
+			return &request, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeTeamRepo) ReleaseTeam(teamID uuid.UUID) error {
+	r.released = append(r.released, teamID)
+	return nil
+}
+
+// fakeCoreStore отдает задачи и контесты из памяти
+type fakeCoreStore struct {
+	contests map[uuid.UUID]models.Contest
+	tasks    map[uuid.UUID]models.Task
+}
+
+func (s *fakeCoreStore) GetCompanyByID(context.Context, uuid.UUID) (*models.Company, error) {
+	return nil, errNotFound
+}
+
+func (s *fakeCoreStore) GetContestByID(_ context.Context, id uuid.UUID) (*models.Contest, error) {
+	contest, ok := s.contests[id]
+	if !ok {
+		return nil, errNotFound
+	}
+	return &contest, nil
+}
+
+func (s *fakeCoreStore) GetTaskByID(_ context.Context, id uuid.UUID) (*models.Task, error) {
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil, errNotFound
+	}
+	return &task, nil
+}
+
+func (s *fakeCoreStore) GetTasksByCompanyID(context.Context, uuid.UUID) ([]models.Task, error) {
+	return nil, nil
+}
+
+func (s *fakeCoreStore) EnqueueEmails(context.Context, []*models.OutboxEmail) (int, error) {
+	return 0, nil
+}
+
+// newTestService создает сервис с командой, одобренной в компании контеста, и задачами этой компании
+func newTestService(t *testing.T, tasks ...models.Task) (*TeamServiceImpl, *fakeTeamRepo, models.Team) {
+	t.Helper()
+	// Присланные файлы сохраняются в ./uploads относительно рабочего каталога
+	t.Chdir(t.TempDir())
+
+	contest := models.Contest{ID: uuid.New(), TaskOrder: models.TaskOrderFixed}
+	companyID := uuid.New()
+	team := models.Team{
+		ID:        uuid.New(),
+		Name:      "Team",
+		Status:    models.TeamStatusActive,
+		ContestID: &contest.ID,
+		CompanyID: &companyID,
+	}
+
+	repo := &fakeTeamRepo{
+		teams: map[uuid.UUID]models.Team{team.ID: team},
+		approvals: []models.ApprovalRequest{{
+			ID:        uuid.New(),
+			TeamID:    team.ID,
+			CompanyID: companyID,
+			ContestID: contest.ID,
+			Status:    models.ApprovalApproved,
+		}},
+	}
+	core := &fakeCoreStore{
+		contests: map[uuid.UUID]models.Contest{contest.ID: contest},
+		tasks:    map[uuid.UUID]models.Task{},
+	}
+	for i, task := range tasks {
+		task.ID = uuid.New()
+		task.ContestID = contest.ID
+		task.CompanyID = companyID
+		task.Order = i
+		repo.tasks = append(repo.tasks, task)
+		core.tasks[task.ID] = task
+	}
+
+	return NewTeamService(repo, core, nil), repo, team
+}
+
+func TestPhotoSubmissionMovesToNextTask(t *testing.T) {
+	svc, repo, team := newTestService(t,
+		models.Task{Question: "Сфотографируйте станцию", AnswerType: models.AnswerTypePhoto},
+		models.Task{Question: "Сколько окон?", AnswerType: models.AnswerTypeText, CorrectAnswer: "7"},
+	)
+
+	photoTask, err := svc.GetTask(team.ID)
+	if err != nil {
+		t.Fatalf("get first task: %v", err)
+	}
+	if photoTask.AnswerType != models.AnswerTypePhoto {
+		t.Fatalf("first task type = %s, want photo", photoTask.AnswerType)
+	}
+
+	// Пока файл не отправлен, выдается та же задача
+	if again, err := svc.GetTask(team.ID); err != nil || again.ID != photoTask.ID {
+		t.Fatalf("get task before submission = %v, %v, want the photo task", again, err)
+	}
+
+	submission, err := svc.SubmitFileAnswer(team.ID, photoTask.ID, "station.jpg", strings.NewReader("jpeg"))
+	if err != nil {
+		t.Fatalf("submit photo: %v", err)
+	}
+
+	next, err := svc.GetTask(team.ID)
+	if err != nil {
+		t.Fatalf("get task after submission: %v", err)
+	}
+	if next.ID == photoTask.ID {
+		t.Fatal("photo task pending review is issued again instead of the next task")
+	}
+	if stored, _ := repo.FindByID(team.ID); stored.CurrentTaskID == nil || *stored.CurrentTaskID != next.ID {
+		t.Fatal("next task is not the team's current task")
+	}
+
+	// Проверка после перехода к следующей задаче закрывает сессию фото и начисляет очки
+	if _, err := svc.ReviewSubmission(submission.CompanyID, submission.ID, true, ""); err != nil {
+		t.Fatalf("review submission: %v", err)
+	}
+	session, err := repo.GetTaskSessionByID(submission.SessionID)
+	if err != nil {
+		t.Fatalf("get photo session: %v", err)
+	}
+	if !session.Finished || !session.IsCorrect {
+		t.Fatalf("photo session finished = %v, correct = %v, want both true", session.Finished, session.IsCorrect)
+	}
+	if stored, _ := repo.FindByID(team.ID); stored.Points != 1 || *stored.CurrentTaskID != next.ID {
+		t.Fatalf("after review points = %d, current task changed = %v", stored.Points, *stored.CurrentTaskID != next.ID)
+	}
+}
+
+func TestPhotoSubmissionOnLastTaskReleasesTeam(t *testing.T) {
+	svc, repo, team := newTestService(t,
+		models.Task{Question: "Сфотографируйте станцию", AnswerType: models.AnswerTypePhoto},
+	)
+
+	photoTask, err := svc.GetTask(team.ID)
+	if err != nil {
+		t.Fatalf("get task: %v", err)
+	}
+	if _, err := svc.SubmitFileAnswer(team.ID, photoTask.ID, "station.jpg", strings.NewReader("jpeg")); err != nil {
+		t.Fatalf("submit photo: %v", err)
+	}
+
+	if _, err := svc.GetTask(team.ID); !errors.Is(err, ErrNoTasksLeft) {
+		t.Fatalf("get task after the last submission = %v, want ErrNoTasksLeft", err)
+	}
+	if len(repo.released) != 1 {
+		t.Fatal("team was not released after its last task")
+	}
+}
+
+func TestNoCheckInFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		// Заявка команды на момент ответа, nil - заявок нет
+		approval func(team models.Team) *models.ApprovalRequest
+		flagged  bool
+	}{
+		{
+			name: "approved in the task's company",
+			approval: func(team models.Team) *models.ApprovalRequest {
+				return &models.ApprovalRequest{CompanyID: *team.CompanyID, Status: models.ApprovalApproved}
+			},
+		},
+		{
+			name:     "no approval",
+			approval: func(models.Team) *models.ApprovalRequest { return nil },
+			flagged:  true,
+		},
+		{
+			name: "released by the company",
+			approval: func(team models.Team) *models.ApprovalRequest {
+				return &models.ApprovalRequest{CompanyID: *team.CompanyID, Status: models.ApprovalReleased}
+			},
+			flagged: true,
+		},
+		{
+			name: "reassigned to another company",
+			approval: func(models.Team) *models.ApprovalRequest {
+				return &models.ApprovalRequest{CompanyID: uuid.New(), Status: models.ApprovalApproved}
+			},
+			flagged: true,
+		},
+		{
+			name: "approval still pending",
+			approval: func(team models.Team) *models.ApprovalRequest {
+				return &models.ApprovalRequest{CompanyID: *team.CompanyID, Status: models.ApprovalPending}
+			},
+			flagged: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo, team := newTestService(t, models.Task{Question: "Сколько окон?", AnswerType: models.AnswerTypeText, CorrectAnswer: "7"})
+			repo.approvals = nil
+			if request := tt.approval(team); request != nil {
+				request.ID = uuid.New()
+				request.TeamID = team.ID
+				repo.approvals = append(repo.approvals, *request)
+			}
+
+			task := repo.tasks[0]
+			session := &models.TeamTaskSession{ID: uuid.New(), TeamID: team.ID, TaskID: task.ID, StartTime: time.Now().Add(-time.Minute)}
+			svc.checkAnswer(&team, &task, session, "7", true)
+
+			flagged := false
+			for _, flag := range repo.flags {
+				if flag.Kind == models.FlagNoCheckIn {
+					flagged = true
+				}
+			}
+			if flagged != tt.flagged {
+				t.Fatalf("no_check_in flagged = %v, want %v (flags %v)", flagged, tt.flagged, repo.flags)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"Cyber-chase/internal/models"
+	"crypto/rand"
+	"encoding/binary"
+	mathrand "math/rand"
+	"sort"
+)
+
+// orderTasks возвращает задачи в порядке выдачи для режима контеста.
+// Для одного и того же набора задач и seed порядок всегда одинаковый.
+func orderTasks(tasks []models.Task, mode string, seed int64) []models.Task {
+	ordered := make([]models.Task, len(tasks))
+	copy(ordered, tasks)
+
+	switch mode {
+	case models.TaskOrderShuffled:
+		// Сначала приводим к порядку, не зависящему от базы, затем перемешиваем
+		sort.Slice(ordered, func(i, j int) bool {
+			return ordered[i].ID.String() < ordered[j].ID.String()
+		})
+		rng := mathrand.New(mathrand.NewSource(seed))
+		rng.Shuffle(len(ordered), func(i, j int) {
+			ordered[i], ordered[j] = ordered[j], ordered[i]
+		})
+	case models.TaskOrderProgressive:
+		sort.SliceStable(ordered, func(i, j int) bool {
+			if ordered[i].Difficulty != ordered[j].Difficulty {
+				return ordered[i].Difficulty < ordered[j].Difficulty
+			}
+			return taskLess(ordered[i], ordered[j])
+		})
+	default:
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return taskLess(ordered[i], ordered[j])
+		})
+	}
+
+	return ordered
+}
+
+// taskLess задает фиксированный порядок: Order, затем время создания, затем ID
+func taskLess(a, b models.Task) bool {
+	if a.Order != b.Order {
+		return a.Order < b.Order
+	}
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID.String() < b.ID.String()
+}
+
+// generateTaskSeed возвращает случайный seed для перемешивания задач команды
+func generateTaskSeed() (int64, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0, err
+	}
+	seed := int64(binary.BigEndian.Uint64(b[:]) >> 1)
+	if seed == 0 {
+		seed = 1
+	}
+	return seed, nil
+}
@@ -1,23 +1,43 @@
 package team
 
 import (
+	"Cyber-chase/internal/models"
+	"Cyber-chase/internal/pkg"
+	"Cyber-chase/internal/pkg/auth"
 	"Cyber-chase/internal/service"
+	"errors"
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 	"net/http"
 )
 
 type TeamHandler struct {
 	service service.TeamService
+	tokens  *auth.Manager
 }
 
-func NewTeamHandler(service service.TeamService) *TeamHandler {
-	return &TeamHandler{service: service}
+func NewTeamHandler(service service.TeamService, tokens *auth.Manager) *TeamHandler {
+	return &TeamHandler{
+		service: service,
+		tokens:  tokens,
+	}
+}
+
+func teamClaims(team *models.Team) auth.Claims {
+	return auth.Claims{
+		Role:             auth.RoleTeam,
+		TokenVersion:     team.TokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: team.ID.String()},
+	}
 }
 
 func (h *TeamHandler) RegisterTeam(c *gin.Context) {
 	var request struct {
 		Email string `json:"email" binding:"required,email"`
 		Name  string `json:"name" binding:"required"`
+		// Email language, ru by default
+		Language string `json:"language"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -25,7 +45,7 @@ func (h *TeamHandler) RegisterTeam(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.RegisterTeam(request.Email, request.Name); err != nil {
+	if err := h.service.RegisterTeam(request.Email, request.Name, request.Language); err != nil {
 		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		return
 	}
@@ -50,8 +70,231 @@ func (h *TeamHandler) LoginTeam(c *gin.Context) {
 		return
 	}
 
+	pair, err := h.tokens.IssuePair(c.Request.Context(), teamClaims(team))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	response := pair.Fields()
+	response["team_id"] = team.ID
+	response["status"] = "authenticated"
+	response["reset_required"] = team.ResetRequired
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *TeamHandler) ChangePassword(c *gin.Context) {
+	teamID := pkg.TeamID(c)
+
+	var request struct {
+		OldPassword string `json:"old_password" binding:"required"`
+		NewPassword string `json:"new_password" binding:"required,min=8"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.ChangePassword(teamID, request.OldPassword, request.NewPassword); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The password change revoked the current token, so a new pair is issued
+	team, err := h.service.GetTeamByID(teamID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	pair, err := h.tokens.IssuePair(c.Request.Context(), teamClaims(team))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	response := pair.Fields()
+	response["status"] = "password_changed"
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *TeamHandler) RequestPasswordReset(c *gin.Context) {
+	var request struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.RequestPasswordReset(request.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "reset_code_sent",
+		"message": "If the team exists, a reset code was sent to its email",
+	})
+}
+
+func (h *TeamHandler) ConfirmPasswordReset(c *gin.Context) {
+	var request struct {
+		Email       string `json:"email" binding:"required,email"`
+		Code        string `json:"code" binding:"required"`
+		NewPassword string `json:"new_password" binding:"required,min=8"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.ResetPasswordWithCode(request.Email, request.Code, request.NewPassword); err != nil {
+		// A wrong code counts as a failed attempt for the rate limiter
+		if errors.Is(err, service.ErrInvalidResetCode) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "password_reset"})
+}
+
+// taskResponse hides the correct answer from teams
+func taskResponse(task *models.Task) gin.H {
+	return gin.H{
+		"id":            task.ID,
+		"question":      task.Question,
+		"question_file": task.QuestionFile,
+		"answer_type":   task.AnswerType,
+		"time_limit":    task.TimeLimit,
+	}
+}
+
+func (h *TeamHandler) GetProfile(c *gin.Context) {
+	teamID := pkg.TeamID(c)
+
+	team, err := h.service.GetTeamByID(teamID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	members, err := h.service.GetTeamMembers(teamID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	memberList := make([]gin.H, 0, len(members))
+	for _, member := range members {
+		memberList = append(memberList, gin.H{
+			"display_name": member.DisplayName,
+			"role":         member.Role,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":              team.ID,
+		"name":            team.Name,
+		"email":           team.Email,
+		"contest_id":      team.ContestID,
+		"company_id":      team.CompanyID,
+		"current_task_id": team.CurrentTaskID,
+		"points":          team.Points,
+		"total_duration":  team.TotalDuration.String(),
+		"reset_required":  team.ResetRequired,
+		"members":         memberList,
+	})
+}
+
+func (h *TeamHandler) JoinContest(c *gin.Context) {
+	teamID := pkg.TeamID(c)
+
+	contest, err := h.service.JoinContest(teamID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "joined",
+		"contest": gin.H{"id": contest.ID, "name": contest.Name},
+	})
+}
+
+func (h *TeamHandler) GetCurrentTask(c *gin.Context) {
+	teamID := pkg.TeamID(c)
+
+	task, session, err := h.service.GetCurrentTask(teamID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"team_id": team.ID,
-		"status":  "authenticated",
+		"task":       taskResponse(task),
+		"started_at": session.StartTime,
+		"attempts":   session.Attempts,
+		"hints_used": session.HintsUsed,
+		"finished":   session.Finished,
 	})
 }
+
+func (h *TeamHandler) GetNextTask(c *gin.Context) {
+	teamID := pkg.TeamID(c)
+
+	task, err := h.service.GetTask(teamID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"task": taskResponse(task)})
+}
+
+func (h *TeamHandler) SubmitAnswer(c *gin.Context) {
+	teamID := pkg.TeamID(c)
+
+	var request struct {
+		TaskID string `json:"task_id" binding:"required,uuid"`
+		Answer string `json:"answer" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	taskID := uuid.MustParse(request.TaskID)
+	correct, err := h.service.SubmitAnswer(teamID, taskID, request.Answer)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{"correct": correct}
+	if session, err := h.service.GetTaskSession(teamID, taskID); err == nil {
+		response["attempts"] = session.Attempts
+		response["finished"] = session.Finished
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *TeamHandler) GetHistory(c *gin.Context) {
+	teamID := pkg.TeamID(c)
+
+	history, err := h.service.GetTeamHistory(teamID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
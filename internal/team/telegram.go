@@ -3,18 +3,17 @@ package team
 import (
 	"Cyber-chase/internal/models"
 	"Cyber-chase/internal/pkg"
+	"Cyber-chase/internal/pkg/ratelimit"
 	"Cyber-chase/internal/service"
-	"bytes"
-	"encoding/json"
 	"fmt"
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
-	"io"
 	"log"
+	"mime"
 	"net/http"
-	"os"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -34,6 +33,12 @@ const (
 	StateReadyToGetTask   = "ready_to_get_task"
 	StateTaskReceived     = "task_received"
 	StateAllTasksComplete = "all_tasks_done"
+	StateJoinCode         = "join_code"
+	StateChangePassOld    = "change_pass_old"
+	StateChangePassNew    = "change_pass_new"
+	StateResetEmail       = "reset_email"
+	StateResetCode        = "reset_code"
+	StateResetPassword    = "reset_password"
 )
 
 // Сессия пользователя
@@ -43,6 +48,11 @@ type UserSession struct {
 	TeamID       string
 	TaskID       string
 	TempTeamName string
+	// Промежуточные данные смены и сброса пароля
+	OldPassword string
+	ResetCode   string
+	// Состояние, в которое нужно вернуться после смены пароля
+	PrevState string
 }
 
 // TelegramBot структура для телеграм бота
@@ -50,19 +60,29 @@ type TelegramBot struct {
 	bot         *tgbotapi.BotAPI
 	teamService service.TeamService
 	sessions    map[int64]*UserSession
+	mu          sync.Mutex
+	// Действия фоновых горутин выполняются в цикле обработки обновлений,
+	// поэтому сессии пользователей меняются только из одной горутины
+	events chan func()
+	// Общий с REST API ограничитель входа и отдельный ограничитель частоты ответов
+	loginLimiter  *ratelimit.Limiter
+	answerLimiter *ratelimit.Limiter
 }
 
 // NewTelegramBot создает новый экземпляр телеграм бота
-func NewTelegramBot(token string, teamService service.TeamService) (*TelegramBot, error) {
+func NewTelegramBot(token string, teamService service.TeamService, loginLimiter *ratelimit.Limiter) (*TelegramBot, error) {
 	bot, err := tgbotapi.NewBotAPI(token)
 	if err != nil {
 		return nil, err
 	}
 
 	return &TelegramBot{
-		bot:         bot,
-		teamService: teamService,
-		sessions:    make(map[int64]*UserSession),
+		bot:           bot,
+		teamService:   teamService,
+		sessions:      make(map[int64]*UserSession),
+		events:        make(chan func(), 64),
+		loginLimiter:  loginLimiter,
+		answerLimiter: ratelimit.NewLimiter(ratelimit.AnswerConfig, ratelimit.NewMemoryStore()),
 	}, nil
 }
 
@@ -73,19 +93,33 @@ func (b *TelegramBot) Start() error {
 
 	updates := b.bot.GetUpdatesChan(u)
 
-	for update := range updates {
-		if update.Message != nil {
-			b.handleMessage(update.Message)
-		} else if update.CallbackQuery != nil {
-			b.handleCallback(update.CallbackQuery)
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				return nil
+			}
+			if update.Message != nil {
+				b.handleMessage(update.Message)
+			} else if update.CallbackQuery != nil {
+				b.handleCallback(update.CallbackQuery)
+			}
+		case event := <-b.events:
+			event()
 		}
 	}
+}
 
-	return nil // или возвращаем реальную ошибку, если есть
+// dispatch передает действие в цикл обработки обновлений
+func (b *TelegramBot) dispatch(event func()) {
+	b.events <- event
 }
 
 // getSession возвращает сессию пользователя, создает новую если не существует
 func (b *TelegramBot) getSession(chatID int64) *UserSession {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	session, exists := b.sessions[chatID]
 	if !exists {
 		session = &UserSession{
@@ -100,14 +134,43 @@ func (b *TelegramBot) getSession(chatID int64) *UserSession {
 func (b *TelegramBot) handleMessage(message *tgbotapi.Message) {
 	session := b.getSession(message.Chat.ID)
 
+	// Ссылка из QR-кода открывает бота командой /start <payload>
+	if message.IsCommand() && message.Command() == "start" && message.CommandArguments() != "" {
+		b.handleDeepLink(message.Chat.ID, session, message.CommandArguments())
+		return
+	}
+
+	// Команды, доступные участнику команды в любом состоянии
+	if session.TeamID != "" && message.IsCommand() && b.handleTeamCommand(message, session) {
+		return
+	}
+
+	// Сброс пароля доступен до входа
+	if session.TeamID == "" && message.Text == "/reset" {
+		if session.Email == "" {
+			b.sendMessage(message.Chat.ID, "Введите email команды:")
+			session.State = StateResetEmail
+			return
+		}
+		b.requestPasswordReset(message.Chat.ID, session)
+		return
+	}
+
 	switch session.State {
 	case StateStart:
 		if message.Text == "/start" {
-			msg := "👋 Добро пожаловать!\nВыберите действие:\n1. Войти - введите email\n2. Регистрация - введите /register"
+			msg := "👋 Добро пожаловать!\nВыберите действие:\n1. Войти - введите email\n2. Регистрация - введите /register\n3. Присоединиться к команде по коду капитана - введите /join"
 			b.sendMessage(message.Chat.ID, msg)
 		} else if message.Text == "/register" {
 			b.sendMessage(message.Chat.ID, "Введите название вашей команды:")
 			session.State = StateRegisterName
+		} else if message.IsCommand() && message.Command() == "join" {
+			if code := message.CommandArguments(); code != "" {
+				b.joinTeam(message, session, code)
+				return
+			}
+			b.sendMessage(message.Chat.ID, "Введите код приглашения от капитана команды:")
+			session.State = StateJoinCode
 		} else {
 			session.Email = strings.TrimSpace(message.Text)
 			b.checkEmailAndProceed(message.Chat.ID, session)
@@ -137,7 +200,12 @@ func (b *TelegramBot) handleMessage(message *tgbotapi.Message) {
 		}
 
 		// Регистрируем команду
-		err := b.teamService.RegisterTeam(email, session.TempTeamName)
+		// Язык писем берем из настроек Telegram
+		language := ""
+		if message.From != nil {
+			language = message.From.LanguageCode
+		}
+		err := b.teamService.RegisterTeam(email, session.TempTeamName, language)
 		if err != nil {
 			b.sendMessage(message.Chat.ID, "❌ Ошибка регистрации: "+err.Error())
 			session.State = StateStart
@@ -157,16 +225,28 @@ func (b *TelegramBot) handleMessage(message *tgbotapi.Message) {
 	case StatePassword:
 		password := strings.TrimSpace(message.Text)
 
+		loginKeys := []string{
+			ratelimit.Key("team", "chat", strconv.FormatInt(message.Chat.ID, 10)),
+			ratelimit.Key("team", "email", session.Email),
+		}
+		if wait, ok := b.loginLimiter.Allow(loginKeys...); !ok {
+			b.sendMessage(message.Chat.ID, fmt.Sprintf("⏳ Слишком много попыток входа. Попробуйте через %s.\nВведите email:", formatWait(wait)))
+			session.State = StateEmail
+			return
+		}
+
 		// Аутентификация команды
 		team, err := b.teamService.AuthenticateTeam(session.Email, password)
 		if err != nil {
-			b.sendMessage(message.Chat.ID, "Неверный email или пароль. Попробуйте снова.\nВведите email:")
+			b.loginLimiter.Hit(loginKeys...)
+			b.sendMessage(message.Chat.ID, "Неверный email или пароль. Попробуйте снова.\nЗабыли пароль? Отправьте /reset\nВведите email:")
 			session.State = StateEmail
 			return
 		}
+		b.loginLimiter.Reset(loginKeys[1])
 
 		// Связываем Telegram ID с командой
-		err = b.teamService.LinkTelegramToTeam(session.Email, message.Chat.ID)
+		err = b.teamService.LinkTelegramToTeam(session.Email, message.Chat.ID, displayName(message.From))
 		if err != nil {
 			b.sendMessage(message.Chat.ID, "Ошибка при привязке Telegram к команде: "+err.Error())
 			session.State = StateStart
@@ -175,12 +255,74 @@ func (b *TelegramBot) handleMessage(message *tgbotapi.Message) {
 
 		session.TeamID = team.ID.String()
 		session.State = StateMenu
+		if team.ResetRequired {
+			b.sendMessage(message.Chat.ID, "🔐 Вы вошли с временным паролем. Смените его командой /password")
+		}
+		if team.Status == models.TeamStatusDisqualified {
+			b.sendDisqualified(message.Chat.ID, team)
+			return
+		}
 		b.sendMainMenu(message.Chat.ID)
 
+	case StateChangePassOld:
+		session.OldPassword = strings.TrimSpace(message.Text)
+		b.sendMessage(message.Chat.ID, fmt.Sprintf("Введите новый пароль (не менее %d символов):", service.MinPasswordLength))
+		session.State = StateChangePassNew
+
+	case StateChangePassNew:
+		err := b.teamService.ChangePassword(
+			models.UUIDFromString(session.TeamID),
+			session.OldPassword,
+			strings.TrimSpace(message.Text),
+		)
+		session.OldPassword = ""
+		session.State = session.PrevState
+		if err != nil {
+			b.sendMessage(message.Chat.ID, "❌ Не удалось сменить пароль: "+err.Error())
+		} else {
+			b.sendMessage(message.Chat.ID, "✅ Пароль изменен")
+		}
+		b.sendMainMenu(message.Chat.ID)
+
+	case StateResetEmail:
+		session.Email = strings.TrimSpace(message.Text)
+		b.requestPasswordReset(message.Chat.ID, session)
+
+	case StateResetCode:
+		session.ResetCode = strings.TrimSpace(message.Text)
+		b.sendMessage(message.Chat.ID, fmt.Sprintf("Введите новый пароль (не менее %d символов):", service.MinPasswordLength))
+		session.State = StateResetPassword
+
+	case StateResetPassword:
+		err := b.teamService.ResetPasswordWithCode(session.Email, session.ResetCode, strings.TrimSpace(message.Text))
+		session.ResetCode = ""
+		if err != nil {
+			b.sendMessage(message.Chat.ID, "❌ Не удалось сбросить пароль: "+err.Error()+"\nЗапросите новый код: /reset")
+			session.State = StateStart
+			return
+		}
+		b.sendMessage(message.Chat.ID, "✅ Пароль изменен. Введите пароль для входа:")
+		session.State = StatePassword
+
+	case StateJoinCode:
+		b.joinTeam(message, session, strings.TrimSpace(message.Text))
+
 	case StateMenu:
 		b.handleMenuCommand(message)
 
 	case StateAnswer:
+		if message.Photo != nil || message.Document != nil {
+			b.handleFileAnswer(message, session)
+			return
+		}
+
+		answerKey := ratelimit.Key("answer", "team", session.TeamID)
+		if wait, ok := b.answerLimiter.Allow(answerKey); !ok {
+			b.sendMessage(message.Chat.ID, fmt.Sprintf("⏳ Слишком много ответов подряд. Следующий ответ можно отправить через %s.", formatWait(wait)))
+			return
+		}
+		b.answerLimiter.Hit(answerKey)
+
 		answer := strings.TrimSpace(message.Text)
 
 		correct, err := b.teamService.SubmitAnswer(
@@ -189,35 +331,64 @@ func (b *TelegramBot) handleMessage(message *tgbotapi.Message) {
 			answer,
 		)
 
+		b.handleAnswerResult(message.Chat.ID, session, correct, err)
+	}
+}
+
+// handleAnswerResult сообщает результат ответа всей команде и выдает следующую задачу, если текущая завершена
+func (b *TelegramBot) handleAnswerResult(chatID int64, session *UserSession, correct bool, err error) {
+	teamID := models.UUIDFromString(session.TeamID)
+
+	if err != nil {
+		b.sendMessage(chatID, "Ошибка при отправке ответа: "+err.Error())
+	} else if correct {
+		b.broadcast(teamID, "✅ Правильный ответ!")
+	} else {
+		b.broadcast(teamID, "❌ Неправильный ответ.")
+	}
+
+	// Проверка: закончена ли задача
+	sessionData, err := b.teamService.GetTaskSession(teamID, models.UUIDFromString(session.TaskID))
+
+	if err == nil && sessionData.Finished {
+		// Пытаемся выдать следующее задание
+		task, err := b.teamService.GetTask(teamID)
 		if err != nil {
-			b.sendMessage(message.Chat.ID, "Ошибка при отправке ответа: "+err.Error())
-		} else if correct {
-			b.sendMessage(message.Chat.ID, "✅ Правильный ответ!")
+			b.broadcast(teamID, "Больше нет доступных задач или ваша сессия завершена.")
+			b.setTeamState(teamID, StateAllTasksComplete)
 		} else {
-			b.sendMessage(message.Chat.ID, "❌ Неправильный ответ.")
+			b.deliverTask(teamID, task)
 		}
+		return
+	}
 
-		// Проверка: закончена ли задача
-		sessionData, err := b.teamService.GetTaskSession(
-			models.UUIDFromString(session.TeamID),
-			models.UUIDFromString(session.TaskID),
-		)
+	session.State = StateTaskReceived
+	b.sendMainMenu(chatID)
+}
 
-		if err == nil && sessionData.Finished {
-			// Пытаемся выдать следующее задание
-			task, err := b.teamService.GetTask(models.UUIDFromString(session.TeamID))
-			if err != nil {
-				b.sendMessage(message.Chat.ID, "Больше нет доступных задач или ваша сессия завершена.")
-			} else {
-				session.TaskID = task.ID.String()
-				b.sendMessage(message.Chat.ID, "Следующая задача:\\n\\n\""+task.Question)
-				return
-			}
-		}
+// handleDeepLink обрабатывает ссылку из QR-кода и засчитывает код как ответ на текущую задачу
+func (b *TelegramBot) handleDeepLink(chatID int64, session *UserSession, payload string) {
+	code, ok := pkg.ParseQRPayload(payload)
+	if !ok {
+		b.sendMessage(chatID, "❌ Неизвестная ссылка")
+		return
+	}
 
-		session.State = StateMenu
-		b.sendMainMenu(message.Chat.ID)
+	if session.TeamID == "" {
+		b.sendMessage(chatID, "Сначала войдите в аккаунт команды, затем отсканируйте QR-код снова.\nВведите email:")
+		session.State = StateStart
+		return
 	}
+
+	team, err := b.teamService.GetTeamByID(models.UUIDFromString(session.TeamID))
+	if err != nil || team.CurrentTaskID == nil {
+		b.sendMessage(chatID, "❌ У команды нет текущей задачи")
+		return
+	}
+	session.TaskID = team.CurrentTaskID.String()
+
+	correct, err := b.teamService.SubmitQRCode(team.ID, code)
+	b.handleAnswerResult(chatID, session, correct, err)
 }
 
 func (b *TelegramBot) checkEmailAndProceed(chatID int64, session *UserSession) {
@@ -245,72 +416,33 @@ func (b *TelegramBot) handleCallback(callback *tgbotapi.CallbackQuery) {
 	session := b.getSession(callback.Message.Chat.ID)
 	b.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
 
+	// Кнопки старых сообщений остаются в чате после выхода или отвязки Telegram
+	teamID := models.UUIDFromString(session.TeamID)
+	if teamID == uuid.Nil && callback.Data != "logout" {
+		b.sendMessage(callback.Message.Chat.ID, "🔒 Сессия завершена. Введите /start чтобы войти заново.")
+		return
+	}
+
+	if companyID, ok := strings.CutPrefix(callback.Data, "req_company:"); ok {
+		b.handleCompanyRequest(callback.Message.Chat.ID, session, companyID)
+		return
+	}
+
 	switch callback.Data {
 	case "join_contest":
-		contest, err := b.teamService.JoinContest(uuid.MustParse(session.TeamID))
+		contest, err := b.teamService.JoinContest(teamID)
 		if err != nil {
 			b.sendMessage(callback.Message.Chat.ID, "❌ Ошибка: "+err.Error())
 			return
 		}
-		b.sendMessage(callback.Message.Chat.ID, fmt.Sprintf("✅ Вы присоединились к контесту: %s", contest.Name))
-		session.State = StateWaitingGeo
-		b.sendMainMenu(callback.Message.Chat.ID)
+		b.broadcast(teamID, fmt.Sprintf("✅ Вы присоединились к контесту: %s", contest.Name))
+		b.setTeamState(teamID, StateWaitingGeo)
 
 	case "send_geo":
-		teamID := uuid.MustParse(session.TeamID)
+		b.sendCompanyChoice(callback.Message.Chat.ID, session)
 
-		// 1. Получаем companyID для команды
-		companyID, err := b.teamService.GetCompanyIDByTeam(teamID)
-		if err != nil {
-			b.sendMessage(callback.Message.Chat.ID, "❌ Команда не привязана к компании: "+err.Error())
-			return
-		}
-
-		// 2. Получаем токен компании
-		token, err := b.getCompanyTokenDirect(companyID)
-		if err != nil {
-			b.sendMessage(callback.Message.Chat.ID, "❌ Ошибка авторизации: "+err.Error())
-			return
-		}
-
-		// 3. Запрашиваем геолокацию
-		locReq, err := http.NewRequest("GET", "http://localhost:8080/api/v1/company/location", nil)
-		if err != nil {
-			b.sendMessage(callback.Message.Chat.ID, "❌ Ошибка создания запроса геолокации: "+err.Error())
-			return
-		}
-		locReq.Header.Set("Authorization", "Bearer "+token)
-
-		client := &http.Client{}
-		locResp, err := client.Do(locReq)
-		if err != nil {
-			b.sendMessage(callback.Message.Chat.ID, "❌ Ошибка запроса геолокации: "+err.Error())
-			return
-		}
-		defer locResp.Body.Close()
-
-		// 4. Обрабатываем ответ
-		locBody, _ := io.ReadAll(locResp.Body)
-		if locResp.StatusCode != http.StatusOK {
-			b.sendMessage(callback.Message.Chat.ID, fmt.Sprintf("❌ Не удалось получить геолокацию: статус %d, ответ: %s",
-				locResp.StatusCode, string(locBody)))
-			return
-		}
-
-		// 5. Парсим данные геолокации
-		var locationResponse struct {
-			MapLink string `json:"map_link"`
-		}
-		if err := json.Unmarshal(locBody, &locationResponse); err != nil {
-			b.sendMessage(callback.Message.Chat.ID, "❌ Ошибка обработки данных геолокации: "+err.Error())
-			return
-		}
-
-		// 6. Отправляем результат пользователю
-		b.sendMessage(callback.Message.Chat.ID, "📍 Геолокация компании:\n"+locationResponse.MapLink)
-		b.sendMessage(callback.Message.Chat.ID, "Ожидайте одобрения...")
-		session.State = StateWaitingApprove
-		go b.awaitApproval(callback.Message.Chat.ID, session)
+	case "waiting_approve":
+		b.sendApprovalStatus(callback.Message.Chat.ID, session)
 
 	case "get_task":
 		b.handleGetTask(callback.Message.Chat.ID, session)
@@ -319,54 +451,20 @@ func (b *TelegramBot) handleCallback(callback *tgbotapi.CallbackQuery) {
 		b.sendMessage(callback.Message.Chat.ID, "✍️ Введите ваш ответ:")
 		session.State = StateAnswer
 
+	case "hint":
+		b.handleHint(callback.Message.Chat.ID, session)
+
+	case "skip_task":
+		b.handleSkipTask(callback.Message.Chat.ID, session)
+
 	case "logout":
+		b.mu.Lock()
 		delete(b.sessions, callback.Message.Chat.ID)
+		b.mu.Unlock()
 		b.sendMessage(callback.Message.Chat.ID, "🚪 Вы вышли. Введите /start чтобы начать заново.")
 	}
 }
 
-func (b *TelegramBot) getCompanyToken(teamID uuid.UUID) (string, error) {
-	// 1. Получить CompanyID через сервис команд
-	companyID, err := b.teamService.GetCompanyIDByTeam(teamID)
-	if err != nil {
-		return "", fmt.Errorf("team company not found: %v", err)
-	}
-
-	// 2. Получить данные компании через сервис
-	companyData, err := b.teamService.GetCompanyCredentials(companyID)
-	if err != nil {
-		return "", fmt.Errorf("company data error: %v", err)
-	}
-
-	// 3. Авторизоваться как компания
-	client := &http.Client{}
-	data := map[string]string{
-		"email":    companyData.Email,
-		"password": companyData.TempPassword,
-	}
-	jsonData, _ := json.Marshal(data)
-
-	resp, err := client.Post(
-		"http://localhost:8080/api/v1/company/login",
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
-	if err != nil || resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("ошибка аутентификации: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// 3. Парсим токен из ответа
-	var result struct {
-		Token string `json:"token"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("ошибка парсинга токена: %v", err)
-	}
-
-	return result.Token, nil
-}
-
 // sendStartMessage отправляет приветственное сообщение
 func (b *TelegramBot) sendStartMessage(chatID int64) {
 	msg := "👋 Добро пожаловать в бот для команд!\nДля авторизации введите email вашей команды:"
@@ -385,7 +483,7 @@ func (b *TelegramBot) sendMainMenu(chatID int64) {
 		})
 	case StateWaitingGeo:
 		buttons = append(buttons, []tgbotapi.InlineKeyboardButton{
-			tgbotapi.NewInlineKeyboardButtonData("Получить гео", "send_geo"),
+			tgbotapi.NewInlineKeyboardButtonData("Выбрать компанию", "send_geo"),
 		})
 	case StateWaitingApprove:
 		buttons = append(buttons, []tgbotapi.InlineKeyboardButton{
@@ -398,9 +496,14 @@ func (b *TelegramBot) sendMainMenu(chatID int64) {
 	case StateTaskReceived:
 		buttons = append(buttons, []tgbotapi.InlineKeyboardButton{
 			tgbotapi.NewInlineKeyboardButtonData("Дать ответ", "submit_answer"),
+			tgbotapi.NewInlineKeyboardButtonData("Подсказка", "hint"),
+		})
+		buttons = append(buttons, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData("Пропустить задание", "skip_task"),
 		})
 	case StateAllTasksComplete:
 		buttons = append(buttons, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData("Следующая компания", "send_geo"),
 			tgbotapi.NewInlineKeyboardButtonData("Выйти", "logout"),
 		})
 	}
@@ -419,7 +522,7 @@ func (b *TelegramBot) handleMenuCommand(message *tgbotapi.Message) {
 	switch message.Text {
 	case "/start":
 		b.sendStartMessage(message.Chat.ID)
-		b.sessions[message.Chat.ID].State = StateEmail
+		b.getSession(message.Chat.ID).State = StateEmail
 	case "/menu":
 		b.sendMainMenu(message.Chat.ID)
 	default:
@@ -427,6 +530,20 @@ func (b *TelegramBot) handleMenuCommand(message *tgbotapi.Message) {
 	}
 }
 
+// formatWait округляет время ожидания до секунд для сообщений пользователю
+func formatWait(wait time.Duration) string {
+	return wait.Round(time.Second).String()
+}
+
+// sendDisqualified сообщает о дисквалификации команды, игровые действия ей недоступны
+func (b *TelegramBot) sendDisqualified(chatID int64, team *models.Team) {
+	text := "⛔ Ваша команда дисквалифицирована и не может продолжать игру."
+	if team.DisqualifyReason != "" {
+		text += "\nПричина: " + team.DisqualifyReason
+	}
+	b.sendMessage(chatID, text)
+}
+
 // handleJoinContest обрабатывает запрос на присоединение к контесту
 func (b *TelegramBot) handleJoinContest(chatID int64, session *UserSession) {
 	teamID := models.UUIDFromString(session.TeamID)
@@ -442,227 +559,458 @@ func (b *TelegramBot) handleJoinContest(chatID int64, session *UserSession) {
 	b.sendMessage(chatID, msg)
 }
 
-func (b *TelegramBot) awaitApproval(chatID int64, session *UserSession) {
-	_, err := b.teamService.GetTeamByEmail(session.Email)
-	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка: команда не найдена")
-		return
-	}
-
-	for i := 0; i < 30; i++ { // до 30 попыток (например, 30 секунд)
+// awaitApproval ждет решения компании по заявке и сообщает об изменении места в очереди
+func (b *TelegramBot) awaitApproval(chatID int64, teamID, requestID uuid.UUID, position int) {
+	for i := 0; i < 900; i++ { // до 30 минут
 		time.Sleep(2 * time.Second)
-		updated, err := b.teamService.GetTeamByEmail(session.Email)
-		if err == nil && updated.CompanyID != nil {
-			b.sendMessage(chatID, "🎉 Ваша команда была одобрена компанией!")
-			session.State = StateReadyToGetTask
-			b.sendMainMenu(chatID)
+		request, current, err := b.teamService.GetApprovalStatus(teamID)
+		if err != nil || request.ID != requestID {
+			// Команда выбрала другую компанию, за новой заявкой следит другой вызов
+			return
+		}
+
+		switch request.Status {
+		case models.ApprovalApproved:
+			// Текст об одобрении рассылает сервис
+			b.dispatch(func() { b.setTeamState(teamID, StateReadyToGetTask) })
+			return
+		case models.ApprovalRejected:
+			b.dispatch(func() { b.setTeamState(teamID, StateWaitingGeo) })
+			return
+		case models.ApprovalPending:
+			if current != position {
+				position = current
+				b.broadcast(teamID, fmt.Sprintf("🕒 Ваше место в очереди: %d", position))
+			}
+		default:
 			return
 		}
 	}
 
-	b.sendMessage(chatID, "⌛ Ожидание одобрения превысило лимит времени. Попробуйте позже.")
-	session.State = StateMenu
-	b.sendMainMenu(chatID)
+	b.sendMessage(chatID, "⌛ Компания пока не рассмотрела заявку. Проверить статус можно кнопкой «Ожидание одобрения...»")
 }
 
-// handleGetTask обрабатывает запрос на получение задачи
-func (b *TelegramBot) handleGetTask(chatID int64, session *UserSession) {
-	task, err := b.teamService.GetTask(uuid.MustParse(session.TeamID))
+// sendCompanyChoice предлагает команде выбрать компанию, к которой она направляется
+func (b *TelegramBot) sendCompanyChoice(chatID int64, session *UserSession) {
+	companies, err := b.teamService.GetAvailableCompanies(models.UUIDFromString(session.TeamID))
 	if err != nil {
-		b.sendMessage(chatID, "❌ Ошибка при получении задачи: "+err.Error())
+		b.sendMessage(chatID, "❌ Ошибка: "+err.Error())
 		return
 	}
 
-	session.TaskID = task.ID.String()
-	session.State = StateTaskReceived
+	if len(companies) == 0 {
+		b.sendMessage(chatID, "🏁 Ваша команда прошла все точки контеста!")
+		return
+	}
 
-	if task.QuestionFile != "" {
-		filePath := pkg.GetFilePath(task.ID, task.QuestionFile)
-		doc := tgbotapi.NewDocument(chatID, tgbotapi.FilePath(filePath))
-		doc.Caption = fmt.Sprintf("Задача:\n\n%s\n\nВремя: %d минут", task.Question, task.TimeLimit)
-		b.bot.Send(doc)
-	} else {
-		b.sendMessage(chatID, fmt.Sprintf("Задача:\n\n%s\n\nВремя: %d минут", task.Question, task.TimeLimit))
+	var buttons [][]tgbotapi.InlineKeyboardButton
+	for _, company := range companies {
+		buttons = append(buttons, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(company.Name, "req_company:"+company.ID.String()),
+		})
 	}
 
-	b.sendMainMenu(chatID)
+	msg := tgbotapi.NewMessage(chatID, "Выберите компанию, к которой направляется команда:")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(buttons...)
+	b.bot.Send(msg)
 }
 
-// sendMessage отправляет сообщение пользователю
-func (b *TelegramBot) sendMessage(chatID int64, text string) {
-	msg := tgbotapi.NewMessage(chatID, text)
-	_, err := b.bot.Send(msg)
+// handleCompanyRequest ставит команду в очередь к выбранной компании и отправляет ее геолокацию
+func (b *TelegramBot) handleCompanyRequest(chatID int64, session *UserSession, rawCompanyID string) {
+	companyID, err := uuid.Parse(rawCompanyID)
 	if err != nil {
-		log.Printf("Error sending message: %v", err)
+		b.sendMessage(chatID, "❌ Некорректная компания")
+		return
 	}
-}
 
-// Helper function to authenticate as a company and get a token
-func (b *TelegramBot) authenticateCompany(email, password string) (string, error) {
-	client := &http.Client{}
+	teamID := models.UUIDFromString(session.TeamID)
+	request, position, err := b.teamService.RequestCompany(teamID, companyID)
+	if err != nil {
+		b.sendMessage(chatID, "❌ Не удалось отправить заявку: "+err.Error())
+		return
+	}
 
-	// Check the actual request structure expected by the server
-	// This structure should match what the server expects to unmarshal
-	data := map[string]interface{}{
-		"email":    email,
-		"password": password,
+	company, err := b.teamService.GetCompanyByID(companyID)
+	if err != nil {
+		b.sendMessage(chatID, "❌ Компания не найдена")
+		return
+	}
+
+	text := fmt.Sprintf("📨 Заявка отправлена компании %s.\nМесто в очереди: %d", company.Name, position)
+	if company.Location != "" {
+		text += "\n📍 Геолокация компании:\n" + company.Location
 	}
+	b.broadcast(teamID, text)
+	b.setTeamState(teamID, StateWaitingApprove)
 
-	jsonData, err := json.Marshal(data)
+	go b.awaitApproval(chatID, teamID, request.ID, position)
+}
+
+// sendApprovalStatus показывает состояние заявки команды
+func (b *TelegramBot) sendApprovalStatus(chatID int64, session *UserSession) {
+	request, position, err := b.teamService.GetApprovalStatus(models.UUIDFromString(session.TeamID))
 	if err != nil {
-		return "", fmt.Errorf("ошибка сериализации JSON: %v", err)
+		b.sendMessage(chatID, "Заявка не найдена, выберите компанию.")
+		session.State = StateWaitingGeo
+		b.sendMainMenu(chatID)
+		return
 	}
 
-	// For debugging - log the request body
-	log.Printf("Authentication request body: %s", string(jsonData))
+	switch request.Status {
+	case models.ApprovalPending:
+		b.sendMessage(chatID, fmt.Sprintf("🕒 Заявка ожидает рассмотрения. Место в очереди: %d", position))
+	case models.ApprovalApproved:
+		session.State = StateReadyToGetTask
+		b.sendMainMenu(chatID)
+	default:
+		session.State = StateWaitingGeo
+		b.sendMainMenu(chatID)
+	}
+}
 
-	resp, err := client.Post(
-		"http://localhost:8080/api/v1/company/login",
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
+// handleGetTask обрабатывает запрос на получение задачи
+func (b *TelegramBot) handleGetTask(chatID int64, session *UserSession) {
+	teamID := models.UUIDFromString(session.TeamID)
+	task, err := b.teamService.GetTask(teamID)
 	if err != nil {
-		return "", fmt.Errorf("ошибка запроса: %v", err)
+		b.sendMessage(chatID, "❌ Ошибка при получении задачи: "+err.Error())
+		return
 	}
-	defer resp.Body.Close()
 
-	// Read the response body for debugging
-	respBody, _ := io.ReadAll(resp.Body)
-	log.Printf("Authentication response (status %d): %s", resp.StatusCode, string(respBody))
+	b.deliverTask(teamID, task)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("ошибка авторизации, статус: %d, ответ: %s", resp.StatusCode, string(respBody))
+// handleSkipTask пропускает текущую задачу со штрафом и выдает следующую
+func (b *TelegramBot) handleSkipTask(chatID int64, session *UserSession) {
+	next, err := b.teamService.SkipTask(models.UUIDFromString(session.TeamID))
+	if err != nil {
+		b.sendMessage(chatID, "❌ Не удалось пропустить задание: "+err.Error())
+		return
 	}
 
-	// Need to create a new reader since we consumed the response body above
-	var result struct {
-		Token string `json:"token"`
+	teamID := models.UUIDFromString(session.TeamID)
+	b.broadcast(teamID, "⏭ Задание пропущено, начислен штраф.")
+
+	if next == nil {
+		b.broadcast(teamID, "Больше нет доступных задач.")
+		b.setTeamState(teamID, StateAllTasksComplete)
+		return
 	}
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return "", fmt.Errorf("ошибка парсинга токена: %v", err)
+
+	b.deliverTask(teamID, next)
+}
+
+// sendTask отправляет текст задачи и файл к ней, если он есть
+func (b *TelegramBot) sendTask(chatID int64, task *models.Task) {
+	text := fmt.Sprintf("Задача:\n\n%s\n\nВремя: %d минут", task.Question, task.TimeLimit)
+	switch task.AnswerType {
+	case models.AnswerTypePhoto:
+		text += "\n\n📷 В качестве ответа отправьте фото или файл"
+	case models.AnswerTypeQR:
+		text += "\n\n🔳 Найдите QR-код на станции и отсканируйте его камерой телефона"
 	}
 
-	return result.Token, nil
+	if task.QuestionFile != "" {
+		filePath := pkg.GetFilePath(task.ID, task.QuestionFile)
+		doc := tgbotapi.NewDocument(chatID, tgbotapi.FilePath(filePath))
+		doc.Caption = text
+		b.bot.Send(doc)
+	} else {
+		b.sendMessage(chatID, text)
+	}
 }
 
-// Alternative implementation that checks company credentials first
-func (b *TelegramBot) authenticateCompany2(companyID uuid.UUID) (string, error) {
-	// 1. Get company data to check what credentials we have
-	company, err := b.teamService.GetCompanyCredentials(companyID)
+// handleFileAnswer скачивает присланное фото или документ и отправляет его на проверку компании
+func (b *TelegramBot) handleFileAnswer(message *tgbotapi.Message, session *UserSession) {
+	var fileID, filename string
+	if message.Document != nil {
+		fileID = message.Document.FileID
+		filename = message.Document.FileName
+		if filename == "" {
+			// Документ может прийти без имени, тогда имя собирается из ID файла и его MIME-типа
+			filename = message.Document.FileUniqueID + mimeExtension(message.Document.MimeType)
+		}
+		if message.Document.FileSize > pkg.MaxSubmissionSize {
+			b.sendMessage(message.Chat.ID, fmt.Sprintf("❌ Файл слишком большой, максимум %d МБ", pkg.MaxSubmissionSize>>20))
+			return
+		}
+	} else {
+		// Telegram присылает несколько размеров фото, последний — самый большой
+		photo := message.Photo[len(message.Photo)-1]
+		fileID = photo.FileID
+		filename = photo.FileUniqueID + ".jpg"
+	}
+
+	fileURL, err := b.bot.GetFileDirectURL(fileID)
 	if err != nil {
-		return "", fmt.Errorf("ошибка получения данных компании: %v", err)
+		b.sendMessage(message.Chat.ID, "❌ Не удалось получить файл: "+err.Error())
+		return
 	}
 
-	// Log credentials for debugging (remove in production)
-	log.Printf("Company credentials - Email: %s, TempPass: %s", company.Email, company.TempPassword)
+	resp, err := http.Get(fileURL)
+	if err != nil {
+		b.sendMessage(message.Chat.ID, "❌ Не удалось скачать файл: "+err.Error())
+		return
+	}
+	defer resp.Body.Close()
 
-	client := &http.Client{}
+	if resp.StatusCode != http.StatusOK {
+		b.sendMessage(message.Chat.ID, fmt.Sprintf("❌ Не удалось скачать файл: статус %d", resp.StatusCode))
+		return
+	}
+	if resp.ContentLength > pkg.MaxSubmissionSize {
+		b.sendMessage(message.Chat.ID, fmt.Sprintf("❌ Файл слишком большой, максимум %d МБ", pkg.MaxSubmissionSize>>20))
+		return
+	}
 
-	// Try different request structures
-	// Version 1: Standard format
-	reqBody1 := map[string]string{
-		"email":    company.Email,
-		"password": company.TempPassword,
+	_, err = b.teamService.SubmitFileAnswer(
+		models.UUIDFromString(session.TeamID),
+		models.UUIDFromString(session.TaskID),
+		filename,
+		resp.Body,
+	)
+	if err != nil {
+		b.sendMessage(message.Chat.ID, "Ошибка при отправке ответа: "+err.Error())
+		session.State = StateTaskReceived
+		b.sendMainMenu(message.Chat.ID)
+		return
 	}
 
-	// Version 2: Using different field names (in case server expects different names)
-	reqBody2 := map[string]string{
-		"email":        company.Email,
-		"tempPassword": company.TempPassword,
+	// Проверка идет вручную, поэтому команда может переходить к следующему заданию
+	teamID := models.UUIDFromString(session.TeamID)
+	b.broadcast(teamID, "📨 Решение отправлено на проверку. Результат придет в этот чат.")
+	b.setTeamState(teamID, StateReadyToGetTask)
+}
+
+// mimeExtension возвращает расширение файла для MIME-типа или пустую строку
+func mimeExtension(mimeType string) string {
+	extensions, err := mime.ExtensionsByType(mimeType)
+	if err != nil || len(extensions) == 0 {
+		return ""
 	}
+	return extensions[0]
+}
 
-	// Try first format
-	jsonData, _ := json.Marshal(reqBody1)
-	resp, err := client.Post(
-		"http://localhost:8080/api/v1/company/login",
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
+// Username возвращает имя бота для ссылок вида t.me/<bot>
+func (b *TelegramBot) Username() string {
+	return b.bot.Self.UserName
+}
 
-	if err != nil || resp.StatusCode != http.StatusOK {
-		// If first format fails, try second format
-		jsonData, _ = json.Marshal(reqBody2)
-		resp, err = client.Post(
-			"http://localhost:8080/api/v1/company/login",
-			"application/json",
-			bytes.NewBuffer(jsonData),
-		)
+// NotifyTeam отправляет сообщение в чаты всех участников команды
+func (b *TelegramBot) NotifyTeam(team *models.Team, text string) {
+	b.broadcast(team.ID, text)
+}
 
-		if err != nil {
-			return "", fmt.Errorf("ошибка запроса: %v", err)
-		}
+// EndSessions забывает сессии отвязанных чатов, чтобы они не могли продолжать игру за команду
+func (b *TelegramBot) EndSessions(chatIDs []int64) {
+	b.mu.Lock()
+	for _, chatID := range chatIDs {
+		delete(b.sessions, chatID)
 	}
+	b.mu.Unlock()
 
-	defer resp.Body.Close()
+	for _, chatID := range chatIDs {
+		b.sendMessage(chatID, "🔌 Telegram отвязан от команды. Введите /start чтобы войти заново.")
+	}
+}
 
-	// Read response body
-	respBody, _ := io.ReadAll(resp.Body)
+// teamChats возвращает чаты всех участников команды
+func (b *TelegramBot) teamChats(teamID uuid.UUID) []int64 {
+	members, err := b.teamService.GetTeamMembers(teamID)
+	if err == nil && len(members) > 0 {
+		chats := make([]int64, 0, len(members))
+		for _, member := range members {
+			chats = append(chats, member.TelegramID)
+		}
+		return chats
+	}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("ошибка авторизации, статус: %d, ответ: %s", resp.StatusCode, string(respBody))
+	// Команды, привязанные до появления участников, знают только чат капитана
+	team, err := b.teamService.GetTeamByID(teamID)
+	if err != nil || team.TelegramID == nil {
+		return nil
 	}
+	return []int64{*team.TelegramID}
+}
 
-	var result struct {
-		Token string `json:"token"`
+// broadcast отправляет сообщение всем участникам команды
+func (b *TelegramBot) broadcast(teamID uuid.UUID, text string) {
+	for _, chatID := range b.teamChats(teamID) {
+		b.sendMessage(chatID, text)
 	}
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return "", fmt.Errorf("ошибка парсинга токена: %v", err)
+}
+
+// setTeamState переводит всех участников команды в состояние и показывает им меню
+func (b *TelegramBot) setTeamState(teamID uuid.UUID, state string) {
+	for _, chatID := range b.teamChats(teamID) {
+		session := b.getSession(chatID)
+		session.TeamID = teamID.String()
+		session.State = state
+		b.sendMainMenu(chatID)
 	}
+}
 
-	return result.Token, nil
+// deliverTask рассылает задачу всем участникам команды
+func (b *TelegramBot) deliverTask(teamID uuid.UUID, task *models.Task) {
+	for _, chatID := range b.teamChats(teamID) {
+		session := b.getSession(chatID)
+		session.TeamID = teamID.String()
+		session.TaskID = task.ID.String()
+		session.State = StateTaskReceived
+		b.sendTask(chatID, task)
+		b.sendMainMenu(chatID)
+	}
 }
 
-func (b *TelegramBot) getCompanyLocation(token string) (string, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", "http://localhost:8080/api/v1/company/location", nil)
-	if err != nil {
-		return "", err
+// handleTeamCommand обрабатывает команды участника команды, доступные в любом состоянии.
+// Возвращает false, если команда не распознана.
+func (b *TelegramBot) handleTeamCommand(message *tgbotapi.Message, session *UserSession) bool {
+	switch message.Command() {
+	case "password":
+		// Не перебиваем уже начатую смену пароля
+		if session.State != StateChangePassOld && session.State != StateChangePassNew {
+			session.PrevState = session.State
+		}
+		b.sendMessage(message.Chat.ID, "Введите текущий пароль:")
+		session.State = StateChangePassOld
+		return true
+	case "history":
+		b.sendHistory(message.Chat.ID, models.UUIDFromString(session.TeamID))
+		return true
+	case "invite":
+		invite, err := b.teamService.CreateInvite(models.UUIDFromString(session.TeamID), message.Chat.ID)
+		if err != nil {
+			b.sendMessage(message.Chat.ID, "❌ "+err.Error())
+			return true
+		}
+		b.sendMessage(message.Chat.ID, fmt.Sprintf(
+			"Код приглашения: %s\nДействует до %s. Участник должен отправить боту /join %s",
+			invite.Code, invite.ExpiresAt.Format("02.01.2006 15:04"), invite.Code))
+		return true
+	case "team":
+		members, err := b.teamService.GetTeamMembers(models.UUIDFromString(session.TeamID))
+		if err != nil {
+			b.sendMessage(message.Chat.ID, "❌ "+err.Error())
+			return true
+		}
+		var sb strings.Builder
+		sb.WriteString("👥 Участники команды:\n")
+		for _, member := range members {
+			role := ""
+			if member.Role == models.MemberRoleCaptain {
+				role = " (капитан)"
+			}
+			sb.WriteString(fmt.Sprintf("• %s%s\n", member.DisplayName, role))
+		}
+		b.sendMessage(message.Chat.ID, sb.String())
+		return true
 	}
-	req.Header.Set("Authorization", "Bearer "+token)
+	return false
+}
 
-	resp, err := client.Do(req)
+// sendHistory отправляет список задач команды с ответами и итогами
+func (b *TelegramBot) sendHistory(chatID int64, teamID uuid.UUID) {
+	history, err := b.teamService.GetTeamHistory(teamID)
 	if err != nil {
-		return "", err
+		b.sendMessage(chatID, "❌ "+err.Error())
+		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("ошибка получения геолокации, статус: %d", resp.StatusCode)
+	if len(history) == 0 {
+		b.sendMessage(chatID, "Команда еще не получала задач.")
+		return
 	}
 
-	var locationResponse struct {
-		MapLink string `json:"map_link"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&locationResponse); err != nil {
-		return "", fmt.Errorf("ошибка обработки данных: %v", err)
+	var sb strings.Builder
+	sb.WriteString("📜 История задач:\n")
+	for i, result := range history {
+		// Telegram ограничивает длину сообщения, поэтому длинную историю шлем частями
+		if sb.Len() > 3500 {
+			b.sendMessage(chatID, sb.String())
+			sb.Reset()
+		}
+
+		status := "⏳ в процессе"
+		switch {
+		case result.Skipped:
+			status = "⏭ пропущена"
+		case result.Finished && result.IsCorrect:
+			status = "✅ решена"
+		case result.Finished:
+			status = "❌ не решена"
+		}
+
+		sb.WriteString(fmt.Sprintf("\n%d. %s\n%s, время %s, попыток %d, очков %d\n",
+			i+1, result.Question, status, result.Duration, result.Attempts, result.Points))
+		for _, answer := range result.Answers {
+			mark := "❌"
+			if answer.IsCorrect {
+				mark = "✅"
+			}
+			sb.WriteString(fmt.Sprintf("   %s %s «%s»\n", answer.CreatedAt.Format("15:04:05"), mark, answer.Answer))
+		}
 	}
+	b.sendMessage(chatID, sb.String())
+}
 
-	return locationResponse.MapLink, nil
+// requestPasswordReset отправляет код сброса пароля на email из сессии
+func (b *TelegramBot) requestPasswordReset(chatID int64, session *UserSession) {
+	if err := b.teamService.RequestPasswordReset(session.Email); err != nil {
+		b.sendMessage(chatID, "❌ "+err.Error())
+		session.State = StateStart
+		return
+	}
+	b.sendMessage(chatID, fmt.Sprintf("📧 Если команда с email %s существует, на него отправлен код. Введите код из письма:", session.Email))
+	session.State = StateResetCode
 }
 
-func (b *TelegramBot) getCompanyTokenDirect(companyID uuid.UUID) (string, error) {
-	// Do a direct database query to get the company info
-	// This is instead of going through the API login flow
-	company, err := b.teamService.GetCompanyByID(companyID)
+// joinTeam присоединяет чат к команде по коду приглашения
+func (b *TelegramBot) joinTeam(message *tgbotapi.Message, session *UserSession, code string) {
+	team, err := b.teamService.JoinTeamByInvite(code, message.Chat.ID, displayName(message.From))
 	if err != nil {
-		return "", fmt.Errorf("failed to get company: %v", err)
+		b.sendMessage(message.Chat.ID, "❌ "+err.Error())
+		session.State = StateStart
+		return
 	}
 
-	// Generate a JWT token directly using the same logic as your API
-	// You'll need to share the JWT secret with this code
-	jwtSecret := os.Getenv("JWT_SECRET") // Replace with your actual JWT secret
+	session.TeamID = team.ID.String()
+	session.Email = team.Email
+	session.State = StateMenu
+	b.sendMessage(message.Chat.ID, fmt.Sprintf("✅ Вы присоединились к команде %s", team.Name))
+	b.sendMainMenu(message.Chat.ID)
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":            company.ID.String(),
-		"role":           "company",
-		"exp":            time.Now().Add(time.Hour * 24).Unix(),
-		"reset_required": company.ResetRequired,
-	})
+// displayName возвращает имя пользователя Telegram для списка участников
+func displayName(user *tgbotapi.User) string {
+	if user == nil {
+		return ""
+	}
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if name == "" {
+		name = user.UserName
+	}
+	return name
+}
 
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+// handleHint открывает следующую подсказку к текущей задаче
+func (b *TelegramBot) handleHint(chatID int64, session *UserSession) {
+	hint, remaining, err := b.teamService.RevealHint(models.UUIDFromString(session.TeamID))
 	if err != nil {
-		return "", fmt.Errorf("failed to generate token: %v", err)
+		b.sendMessage(chatID, "❌ "+err.Error())
+		return
 	}
 
-	return tokenString, nil
+	msg := fmt.Sprintf("💡 Подсказка %d:\n\n%s\n\nСтоимость подсказки: %d очк. Осталось подсказок: %d",
+		hint.Position, hint.Text, b.teamService.HintCost(), remaining)
+	b.broadcast(models.UUIDFromString(session.TeamID), msg)
+	b.sendMainMenu(chatID)
+}
+
+// sendMessage отправляет сообщение пользователю
+func (b *TelegramBot) sendMessage(chatID int64, text string) {
+	msg := tgbotapi.NewMessage(chatID, text)
+	_, err := b.bot.Send(msg)
+	if err != nil {
+		log.Printf("Error sending message: %v", err)
+	}
 }